@@ -1,49 +1,74 @@
 package main
 
 import (
+	"crypto/subtle"
 	"io/ioutil"
-	"time"
+	"net/http"
+	"os"
+	"path"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 
 	"encoding/json"
 
 	config "github.com/aberestyak/gitlab-issue-bot/internal/config"
+	hooks "github.com/aberestyak/gitlab-issue-bot/internal/hooks"
 	parser "github.com/aberestyak/gitlab-issue-bot/internal/parser"
+	poller "github.com/aberestyak/gitlab-issue-bot/internal/poller"
+	storage "github.com/aberestyak/gitlab-issue-bot/internal/storage"
+	telegram "github.com/aberestyak/gitlab-issue-bot/internal/telegram"
 	logger "github.com/aberestyak/gitlab-issue-bot/pkg/logger"
 	"github.com/gin-gonic/gin"
 	"github.com/xanzy/go-gitlab"
-	tb "gopkg.in/tucnak/telebot.v2"
 )
 
 var (
-	bot          *tb.Bot
-	gitlabClient *gitlab.Client
-	mainLogger   = log.WithFields(log.Fields{
+	bot        *telegram.Bot
+	instances  []config.GitlabInstance
+	mainLogger = log.WithFields(log.Fields{
 		"component": "Main",
 	})
 )
 
 func main() {
 	logger.Init()
+	if len(os.Args) > 1 && os.Args[1] == "hooks" {
+		os.Exit(hooks.Run(os.Args[2:], os.Stdout))
+	}
 	botConfig := config.GetConfig()
-	gitlabClient = config.InitGitlabClient(botConfig.GitlabToken, botConfig.GitlabURL)
+	gitlabClients := map[string]*gitlab.Client{}
+	for _, instance := range botConfig.Instances {
+		gitlabClients[instance.Name] = config.InitGitlabClient(instance.Token, instance.URL)
+	}
+	instances = botConfig.Instances
 
-	bot, _ = tb.NewBot(tb.Settings{
-		Token:  botConfig.TelegramToken,
-		Poller: &tb.LongPoller{Timeout: 5 * time.Second},
-	})
-	bot.Handle("/start", func(m *tb.Message) {
-		mainLogger.Infof("User %s with ID %d has joined", m.Chat.Username, m.Chat.ID)
-		if _, err := bot.Send(m.Chat, "You are now subscribed for issues updates!"); err != nil {
-			log.Printf("Error while send message: %s", err.Error())
-		}
-	})
+	store, err := storage.Open(botConfig.StoragePath)
+	if err != nil {
+		mainLogger.Fatalf("Can't open storage: %s", err.Error())
+	}
+
+	bot, err = telegram.NewBot(botConfig, gitlabClients, store)
+	if err != nil {
+		mainLogger.Fatalf("Can't create telegram bot: %s", err.Error())
+	}
 	go bot.Start()
+	if len(botConfig.PollProjects) > 0 || len(botConfig.PollGroups) > 0 {
+		if len(botConfig.Instances) > 1 {
+			mainLogger.Warnf("Only projects of %s instance are polled, POLL_PROJECTS and POLL_GROUPS of additional instances are not supported", config.DefaultInstance)
+		}
+		go poller.NewPoller(botConfig, gitlabClients[config.DefaultInstance], store, bot.Notify).Start()
+	}
 
 	router := gin.New()
 	router.Use(gin.LoggerWithFormatter(config.GinLogger))
 	router.POST(botConfig.ListenLocation, handlingPOST)
+	for index := range botConfig.Instances[1:] {
+		instance := &botConfig.Instances[index+1]
+		router.POST(path.Join(botConfig.ListenLocation, instance.Name), func(c *gin.Context) {
+			handleWebhook(c, instance)
+		})
+	}
 	router.GET("/health/readiness", func(c *gin.Context) { c.Status(200) })
 	router.GET("/health/liveness", func(c *gin.Context) { c.Status(200) })
 
@@ -52,10 +77,27 @@ func main() {
 	}
 }
 
-// handlingPOST - handle POST requests
+// handlingPOST - handle POST requests to default location. Instance is chosen by X-Gitlab-Instance header
 func handlingPOST(c *gin.Context) {
-	var notification string
-	var issueID int
+	instance := &instances[0]
+	if instanceURL := c.GetHeader("X-Gitlab-Instance"); instanceURL != "" {
+		for index := range instances {
+			if sameURL(instances[index].URL, instanceURL) {
+				instance = &instances[index]
+				break
+			}
+		}
+	}
+	handleWebhook(c, instance)
+}
+
+// handleWebhook - check webhook secret of instance and notify about event
+func handleWebhook(c *gin.Context, instance *config.GitlabInstance) {
+	if instance.WebhookSecret != "" && subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Gitlab-Token")), []byte(instance.WebhookSecret)) != 1 {
+		mainLogger.Warnf("Webhook with wrong secret for instance %s from %s", instance.Name, c.ClientIP())
+		c.Status(http.StatusUnauthorized)
+		return
+	}
 	body, err := ioutil.ReadAll(c.Request.Body)
 	if err != nil {
 		mainLogger.Fatalf(err.Error())
@@ -63,42 +105,17 @@ func handlingPOST(c *gin.Context) {
 	issue, err := parser.ParseBody(body)
 	if err != nil {
 		mainLogger.Errorf(err.Error())
+		return
 	}
+	issue.Instance = instance.Name
 	// Marshal only for debug
 	issueByte, _ := json.MarshalIndent(issue, "", "    ")
 	mainLogger.Debugf("Parsed webhook body: %s", string(issueByte))
 
-	if issue.IssueBody != nil {
-		if err := issue.IssueBody.ConvIDsToNames(gitlabClient); err != nil {
-			mainLogger.Errorf("Can't get gitlab user names from IDs: %s", err.Error())
-		}
-		notification = issue.IssueBody.BeautifyNotification()
-		issueID = issue.IssueBody.ObjectAttributes.ID
-	} else if issue.IssueNote != nil {
-		if err := issue.IssueNote.ConvIDsToNames(gitlabClient); err != nil {
-			mainLogger.Errorf("Can't get gitlab user names from IDs: %s", err.Error())
-		}
-		notification = issue.IssueNote.BeautifyNotification()
-		issueID = issue.IssueNote.Issue.ID
-	} else {
-		mainLogger.Errorln("Can't determine event type, nor issue or comment")
-	}
-
-	botUsers, err := issue.CreateUsersList(gitlabClient)
-	if err != nil {
-		mainLogger.Fatalf(err.Error())
-	}
+	bot.Notify(issue)
+}
 
-	for _, botUser := range botUsers {
-		if botUser.TelegramID != 0 {
-			user := &tb.User{ID: botUser.TelegramID}
-			if _, err := bot.Send(user, string(notification), &tb.SendOptions{ParseMode: tb.ModeMarkdownV2}); err != nil {
-				mainLogger.Errorf("Issue #%d. Error when sending notification to user %s: %s", issueID, botUser.Name, err)
-			} else {
-				mainLogger.Infof("Issue #%d. Notifaction was sent to user %s", issueID, botUser.Name)
-			}
-		} else {
-			mainLogger.Infof("Issue #%d. Can't send notifaction sent to user %s", issueID, botUser.Name)
-		}
-	}
+// sameURL - compare instance URLs ignoring trailing slashes
+func sameURL(configured string, received string) bool {
+	return strings.TrimRight(configured, "/") == strings.TrimRight(received, "/")
 }
@@ -1,7 +1,15 @@
 package config
 
 import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -12,39 +20,100 @@ import (
 
 // BotConfig - telegram bot configuration
 type BotConfig struct {
-	ListenPort     string
-	ListenLocation string
-	TelegramToken  string
-	GitlabToken    string
-	GitlabURL      string
+	ListenPort         string
+	ListenLocation     string
+	TelegramToken      string
+	GitlabToken        string
+	GitlabURL          string
+	CallbackSecret     []byte
+	LabelShortlist     []string
+	StoragePath        string
+	GitlabSudo         bool
+	ThreadExpiry       time.Duration
+	NotificationStyle  string
+	UrgentLabels       []string
+	TodoPollInterval   time.Duration
+	Routes             []ChatRoute
+	LowPriorityLabels  []string
+	CriticalLabels     []string
+	RepingInterval     time.Duration
+	RepingLimit        int
+	Instances          []GitlabInstance
+	OnboardingChats    []int64
+	AdminIDs           []int
+	RedactConfidential bool
+	PollProjects       []string
+	PollGroups         []string
+	PollInterval       time.Duration
+}
+
+// DefaultInstance - name of instance configured by GITLAB_URL and GITLAB_TOKEN
+const DefaultInstance = "default"
+
+// GitlabInstance - gitlab server sending events to the bot
+type GitlabInstance struct {
+	Name          string
+	URL           string
+	Token         string
+	WebhookSecret string
+}
+
+// ChatRoute - group chats and channels receiving events of projects matching pattern
+type ChatRoute struct {
+	Pattern string
+	ChatIDs []int64
 }
 
 const (
 	defaultListenPort     = ":8080"
 	defaultListenLocation = "/"
 	defaultGitlabURL      = "https://gitlab.com"
+	defaultStoragePath    = "gitlab-issue-bot.json"
+	defaultThreadExpiry   = 7 * 24 * time.Hour
+	defaultStyle          = "messages"
+	defaultUrgentLabels   = "priority::1,incident"
+	defaultTodoPoll       = 5 * time.Minute
+	defaultCriticalLabels = "severity::1"
+	defaultRepingInterval = 30 * time.Minute
+	defaultRepingLimit    = 10
+	defaultPollInterval   = time.Minute
 )
 
 var (
-	configLogger = log.WithFields(log.Fields{
+	instanceNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	configLogger       = log.WithFields(log.Fields{
 		"component": "ConfigInit",
 	})
 )
 
-// GetConfig - get environment variables for configuring bot
+// GetConfig - get environment variables for configuring bot, exit if they are wrong
 func GetConfig() BotConfig {
+	config, err := LoadConfig()
+	if err != nil {
+		configLogger.Fatalln(err.Error())
+	}
+	return config
+}
+
+// LoadConfig - get environment variables and CONFIG_FILE for configuring bot
+func LoadConfig() (BotConfig, error) {
 	config := BotConfig{}
+	if err := loadConfigFile(); err != nil {
+		return config, err
+	}
 	telegramToken, telegramTokenSet := os.LookupEnv("TELEGRAM_TOKEN")
 	if !telegramTokenSet {
-		configLogger.Fatalf("Environment variable TELEGRAM_TOKEN not set!")
+		return config, errors.New("environment variable TELEGRAM_TOKEN not set")
 	}
 	config.TelegramToken = telegramToken
 
-	gitlabToken, gitlabTokenSet := os.LookupEnv("GITLAB_TOKEN")
-	if !gitlabTokenSet {
-		configLogger.Fatalf("Environment variable GITLAB_TOKEN not set!")
+	instances, err := loadInstances()
+	if err != nil {
+		return config, err
 	}
-	config.GitlabToken = gitlabToken
+	config.Instances = instances
+	config.GitlabToken = config.Instances[0].Token
+	config.GitlabURL = config.Instances[0].URL
 
 	listenPort, portSet := os.LookupEnv("LISTEN_PORT")
 	if !portSet {
@@ -54,14 +123,6 @@ func GetConfig() BotConfig {
 		config.ListenPort = ":" + listenPort
 	}
 
-	gitlabURL, gitlabURLSet := os.LookupEnv("GITLAB_URL")
-	if !gitlabURLSet {
-		configLogger.Logger.Infof("Environment variable GITLAB_URL not set, use default: %s", defaultGitlabURL)
-		config.GitlabURL = defaultGitlabURL
-	} else {
-		config.GitlabURL = gitlabURL
-	}
-
 	listenLocation, locationSet := os.LookupEnv("LISTEN_LOCATION")
 	if !locationSet {
 		configLogger.Logger.Infof("Environment variable LISTEN_LOCATION not set, use default: %s", defaultListenLocation)
@@ -69,7 +130,270 @@ func GetConfig() BotConfig {
 	} else {
 		config.ListenLocation = listenLocation
 	}
-	return config
+
+	callbackSecret, callbackSecretSet := os.LookupEnv("CALLBACK_SECRET")
+	if !callbackSecretSet {
+		configLogger.Logger.Infof("Environment variable CALLBACK_SECRET not set, derive it from TELEGRAM_TOKEN")
+		secret := sha256.Sum256([]byte(telegramToken))
+		config.CallbackSecret = secret[:]
+	} else {
+		config.CallbackSecret = []byte(callbackSecret)
+	}
+
+	config.LabelShortlist = splitList(os.Getenv("LABEL_SHORTLIST"))
+
+	urgentLabels, urgentLabelsSet := os.LookupEnv("URGENT_LABELS")
+	if !urgentLabelsSet {
+		configLogger.Logger.Infof("Environment variable URGENT_LABELS not set, use default: %s", defaultUrgentLabels)
+		urgentLabels = defaultUrgentLabels
+	}
+	config.UrgentLabels = splitList(urgentLabels)
+
+	config.LowPriorityLabels = splitList(os.Getenv("LOW_PRIORITY_LABELS"))
+	criticalLabels, criticalLabelsSet := os.LookupEnv("CRITICAL_LABELS")
+	if !criticalLabelsSet {
+		configLogger.Logger.Infof("Environment variable CRITICAL_LABELS not set, use default: %s", defaultCriticalLabels)
+		criticalLabels = defaultCriticalLabels
+	}
+	config.CriticalLabels = splitList(criticalLabels)
+
+	storagePath, storagePathSet := os.LookupEnv("STORAGE_PATH")
+	if !storagePathSet {
+		configLogger.Logger.Infof("Environment variable STORAGE_PATH not set, use default: %s", defaultStoragePath)
+		config.StoragePath = defaultStoragePath
+	} else {
+		config.StoragePath = storagePath
+	}
+
+	config.GitlabSudo = parseBool("GITLAB_SUDO")
+	config.RedactConfidential = parseBool("REDACT_CONFIDENTIAL")
+	config.ThreadExpiry = parseDuration("THREAD_EXPIRY", defaultThreadExpiry)
+	config.TodoPollInterval = parseDuration("TODO_POLL_INTERVAL", defaultTodoPoll)
+	config.RepingInterval = parseDuration("REPING_INTERVAL", defaultRepingInterval)
+	config.RepingLimit = parseInt("REPING_LIMIT", defaultRepingLimit)
+	config.PollInterval = parseDuration("POLL_INTERVAL", defaultPollInterval)
+	config.PollProjects = splitList(os.Getenv("POLL_PROJECTS"))
+	config.PollGroups = splitList(os.Getenv("POLL_GROUPS"))
+	config.Routes = parseRoutes(os.Getenv("ROUTES"))
+	config.OnboardingChats = parseChatIDs("ONBOARDING_CHATS", os.Getenv("ONBOARDING_CHATS"))
+	for _, chatID := range parseChatIDs("ADMIN_IDS", os.Getenv("ADMIN_IDS")) {
+		config.AdminIDs = append(config.AdminIDs, int(chatID))
+	}
+
+	notificationStyle, notificationStyleSet := os.LookupEnv("NOTIFICATION_STYLE")
+	if !notificationStyleSet {
+		configLogger.Logger.Infof("Environment variable NOTIFICATION_STYLE not set, use default: %s", defaultStyle)
+		config.NotificationStyle = defaultStyle
+	} else {
+		config.NotificationStyle = notificationStyle
+	}
+	return config, nil
+}
+
+// GetInstances - get gitlab instances, the default one goes first, exit if they are wrong.
+// Used without the rest of configuration by CLI subcommands
+func GetInstances() []GitlabInstance {
+	err := loadConfigFile()
+	var instances []GitlabInstance
+	if err == nil {
+		instances, err = loadInstances()
+	}
+	if err != nil {
+		configLogger.Fatalln(err.Error())
+	}
+	return instances
+}
+
+// loadInstances - get default gitlab instance and additional ones from GITLAB_INSTANCES
+func loadInstances() ([]GitlabInstance, error) {
+	gitlabToken, gitlabTokenSet := os.LookupEnv("GITLAB_TOKEN")
+	if !gitlabTokenSet {
+		return nil, errors.New("environment variable GITLAB_TOKEN not set")
+	}
+	gitlabURL, gitlabURLSet := os.LookupEnv("GITLAB_URL")
+	if !gitlabURLSet {
+		configLogger.Logger.Infof("Environment variable GITLAB_URL not set, use default: %s", defaultGitlabURL)
+		gitlabURL = defaultGitlabURL
+	}
+	instances, err := parseInstances(os.Getenv("GITLAB_INSTANCES"))
+	if err != nil {
+		return nil, err
+	}
+	return append([]GitlabInstance{{
+		Name:          DefaultInstance,
+		URL:           gitlabURL,
+		Token:         gitlabToken,
+		WebhookSecret: os.Getenv("GITLAB_WEBHOOK_SECRET"),
+	}}, instances...), nil
+}
+
+// configFile - keys set from CONFIG_FILE and values they had in environment before
+var configFile = struct {
+	sync.Mutex
+	overridden map[string]*string
+}{overridden: map[string]*string{}}
+
+// loadConfigFile - set environment variables from KEY=VALUE lines of CONFIG_FILE, if set.
+// Called on every LoadConfig, so file changes are picked up by reload. Keys removed from
+// file get their environment values back
+func loadConfigFile() error {
+	path, pathSet := os.LookupEnv("CONFIG_FILE")
+	if !pathSet {
+		return nil
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("can't read config file %s: %w", path, err)
+	}
+	values := map[string]string{}
+	for number, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return fmt.Errorf("wrong line %d in config file %s", number+1, path)
+		}
+		values[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	configFile.Lock()
+	defer configFile.Unlock()
+	for key, original := range configFile.overridden {
+		if _, kept := values[key]; kept {
+			continue
+		}
+		if original != nil {
+			os.Setenv(key, *original)
+		} else {
+			os.Unsetenv(key)
+		}
+		delete(configFile.overridden, key)
+	}
+	for key, value := range values {
+		if _, overridden := configFile.overridden[key]; !overridden {
+			var original *string
+			if current, found := os.LookupEnv(key); found {
+				original = &current
+			}
+			configFile.overridden[key] = original
+		}
+		os.Setenv(key, value)
+	}
+	return nil
+}
+
+// parseBool - get boolean environment variable, false if not set or invalid
+func parseBool(name string) bool {
+	value, valueSet := os.LookupEnv(name)
+	if !valueSet {
+		return false
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		configLogger.Errorf("Environment variable %s has wrong value %s, use false", name, value)
+		return false
+	}
+	return parsed
+}
+
+// parseDuration - get duration environment variable, default if not set or invalid
+func parseDuration(name string, defaultValue time.Duration) time.Duration {
+	value, valueSet := os.LookupEnv(name)
+	if !valueSet {
+		configLogger.Logger.Infof("Environment variable %s not set, use default: %s", name, defaultValue)
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		configLogger.Errorf("Environment variable %s has wrong value %s, use default: %s", name, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// parseInt - get non-negative integer environment variable, default if not set or invalid
+func parseInt(name string, defaultValue int) int {
+	value, valueSet := os.LookupEnv(name)
+	if !valueSet {
+		configLogger.Logger.Infof("Environment variable %s not set, use default: %d", name, defaultValue)
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		configLogger.Errorf("Environment variable %s has wrong value %s, use default: %d", name, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// parseInstances - read GITLAB_<NAME>_URL, GITLAB_<NAME>_TOKEN and GITLAB_<NAME>_WEBHOOK_SECRET
+// of additional instances listed in GITLAB_INSTANCES
+func parseInstances(value string) ([]GitlabInstance, error) {
+	var instances []GitlabInstance
+	for _, name := range splitList(value) {
+		if !instanceNameRegexp.MatchString(name) || name == DefaultInstance {
+			return nil, fmt.Errorf("wrong instance name %s in GITLAB_INSTANCES, use letters, digits, - and _", name)
+		}
+		prefix := "GITLAB_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		instance := GitlabInstance{
+			Name:          name,
+			URL:           os.Getenv(prefix + "URL"),
+			Token:         os.Getenv(prefix + "TOKEN"),
+			WebhookSecret: os.Getenv(prefix + "WEBHOOK_SECRET"),
+		}
+		if instance.URL == "" || instance.Token == "" {
+			return nil, fmt.Errorf("environment variables %sURL and %sTOKEN must be set for instance %s", prefix, prefix, name)
+		}
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
+// parseRoutes - parse routes like "payments/*=-1001234,-1005678;platform/api=-1009876"
+func parseRoutes(value string) []ChatRoute {
+	var routes []ChatRoute
+	for _, rule := range strings.Split(value, ";") {
+		if rule = strings.TrimSpace(rule); rule == "" {
+			continue
+		}
+		parts := strings.SplitN(rule, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			configLogger.Errorf("Wrong route %s in ROUTES, skip it", rule)
+			continue
+		}
+		routes = append(routes, ChatRoute{
+			Pattern: strings.TrimSpace(parts[0]),
+			ChatIDs: parseChatIDs("ROUTES", parts[1]),
+		})
+	}
+	return routes
+}
+
+// parseChatIDs - parse comma separated telegram chat IDs, skipping wrong ones
+func parseChatIDs(name string, value string) []int64 {
+	var chatIDs []int64
+	for _, chat := range splitList(value) {
+		chatID, err := strconv.ParseInt(chat, 10, 64)
+		if err != nil {
+			configLogger.Errorf("Wrong chat ID %s in %s, skip it", chat, name)
+			continue
+		}
+		chatIDs = append(chatIDs, chatID)
+	}
+	return chatIDs
+}
+
+// splitList - split comma separated environment variable value
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
 }
 
 // InitGitlabClient - initialize gitlab client
@@ -2,6 +2,7 @@ package gitlabuserapi
 
 import (
 	"fmt"
+	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
@@ -45,23 +46,31 @@ func GetTgIDByGitlabID(gitlabUserID int, gitlabClient *gitlab.Client) (int, erro
 
 // GetTgIDByGitlabUsername - get user telegram ID from gitlab BIO by username
 func GetTgIDByGitlabUsername(gitlabUsername string, gitlabClient *gitlab.Client) (int, error) {
+	telegramUserID, _, err := GetIDsByGitlabUsername(gitlabUsername, gitlabClient)
+	return telegramUserID, err
+}
+
+// GetIDsByGitlabUsername - get user telegram ID from gitlab BIO and gitlab ID by username.
+// Both are zero if there is no such user
+func GetIDsByGitlabUsername(gitlabUsername string, gitlabClient *gitlab.Client) (int, int, error) {
 	var telegramUserID int
 	gitlabUser, _, err := gitlabClient.Users.ListUsers(&gitlab.ListUsersOptions{Username: &gitlabUsername}, nil)
 	if err != nil {
 		gitlabAPILogger.Errorf("Error when trying ListUsers: %s", err.Error())
-		return 0, err
+		return 0, 0, err
 	}
-	if len(gitlabUser) > 0 {
-		if strings.Contains(gitlabUser[0].Bio, telegramIDKey) {
-			telegramUserID, err = getTelegramIDFromBIO(gitlabUser[0].Bio)
-			if err != nil {
-				return 0, fmt.Errorf("%s for user with gitlab username %s", err.Error(), gitlabUsername)
-			}
-		} else {
-			gitlabAPILogger.Warnf("Can't find Telegram_ID in user's %s BIO", gitlabUsername)
+	if len(gitlabUser) == 0 {
+		return 0, 0, nil
+	}
+	if strings.Contains(gitlabUser[0].Bio, telegramIDKey) {
+		telegramUserID, err = getTelegramIDFromBIO(gitlabUser[0].Bio)
+		if err != nil {
+			return 0, 0, fmt.Errorf("%s for user with gitlab username %s", err.Error(), gitlabUsername)
 		}
+	} else {
+		gitlabAPILogger.Warnf("Can't find Telegram_ID in user's %s BIO", gitlabUsername)
 	}
-	return telegramUserID, nil
+	return telegramUserID, gitlabUser[0].ID, nil
 }
 
 // GetUserNameByID - get gitlab user name by it's ID
@@ -78,6 +87,32 @@ func GetUserNameByID(gitlabUserID int, gitlabClient *gitlab.Client) (string, err
 	return gitlabUser.Name, nil
 }
 
+// GetUserByUsername - get gitlab user by username, nil if there is no such user
+func GetUserByUsername(gitlabUsername string, gitlabClient *gitlab.Client) (*gitlab.User, error) {
+	gitlabUsers, _, err := gitlabClient.Users.ListUsers(&gitlab.ListUsersOptions{Username: &gitlabUsername}, nil)
+	if err != nil {
+		gitlabAPILogger.Errorf("Error when trying ListUsers: %s", err.Error())
+		return nil, err
+	}
+	if len(gitlabUsers) == 0 {
+		return nil, nil
+	}
+	return gitlabUsers[0], nil
+}
+
+// GetAccessLevel - get user's access level to the project including inherited membership
+func GetAccessLevel(projectID int, gitlabUserID int, gitlabClient *gitlab.Client) (gitlab.AccessLevelValue, error) {
+	member, response, err := gitlabClient.ProjectMembers.GetInheritedProjectMember(projectID, gitlabUserID, nil)
+	if err != nil {
+		if response != nil && response.StatusCode == http.StatusNotFound {
+			return gitlab.NoPermissions, nil
+		}
+		gitlabAPILogger.Errorf("Error when trying GetInheritedProjectMember: %s", err.Error())
+		return gitlab.NoPermissions, err
+	}
+	return member.AccessLevel, nil
+}
+
 func getTelegramIDFromBIO(BIO string) (int, error) {
 	var telegramUserID int
 	// Replace, because users unterstood literally "<telegram_id>". Sigh
@@ -91,3 +126,34 @@ func getTelegramIDFromBIO(BIO string) (int, error) {
 
 	return telegramUserID, nil
 }
+
+// ListProjects - get projects by paths and all projects of groups including subgroups
+func ListProjects(projectPaths []string, groups []string, gitlabClient *gitlab.Client) ([]*gitlab.Project, error) {
+	var projects []*gitlab.Project
+	for _, projectPath := range projectPaths {
+		project, _, err := gitlabClient.Projects.GetProject(projectPath, nil)
+		if err != nil {
+			return nil, fmt.Errorf("project %s: %w", projectPath, err)
+		}
+		projects = append(projects, project)
+	}
+	for _, group := range groups {
+		options := &gitlab.ListGroupProjectsOptions{
+			ListOptions:      gitlab.ListOptions{PerPage: 100, Page: 1},
+			IncludeSubgroups: gitlab.Bool(true),
+			Archived:         gitlab.Bool(false),
+		}
+		for {
+			groupProjects, response, err := gitlabClient.Groups.ListGroupProjects(group, options)
+			if err != nil {
+				return nil, fmt.Errorf("group %s: %w", group, err)
+			}
+			projects = append(projects, groupProjects...)
+			if response.NextPage == 0 {
+				break
+			}
+			options.Page = response.NextPage
+		}
+	}
+	return projects, nil
+}
@@ -0,0 +1,277 @@
+package hooks
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	config "github.com/aberestyak/gitlab-issue-bot/internal/config"
+	gitlabUserAPI "github.com/aberestyak/gitlab-issue-bot/internal/gitlabAPI"
+	log "github.com/sirupsen/logrus"
+	"github.com/xanzy/go-gitlab"
+)
+
+var hooksLogger = log.WithFields(log.Fields{
+	"component": "Hooks",
+})
+
+// recentEventsLimit - how many latest deliveries of hook are checked for failures
+const recentEventsLimit = 20
+
+const usage = `Usage: gitlab-issue-bot hooks sync|check|remove -url <webhook URL> [-projects group/a,group/b] [-group <group>] [-instance <name>]
+
+  sync    create bot webhook or update its secret and events on every project
+  check   report missing, disabled and failing webhooks and wrong events
+  remove  delete bot webhook from every project
+`
+
+// projectHook - project webhook with fields unknown to go-gitlab
+type projectHook struct {
+	gitlab.ProjectHook
+	AlertStatus   string     `json:"alert_status"`
+	DisabledUntil *time.Time `json:"disabled_until"`
+}
+
+// hookEvent - recent webhook delivery. Response status is HTTP code or error text
+type hookEvent struct {
+	ResponseStatus interface{} `json:"response_status"`
+	CreatedAt      *time.Time  `json:"created_at"`
+}
+
+// failed - delivery got no response or error response
+func (event hookEvent) failed() bool {
+	code, isCode := event.ResponseStatus.(float64)
+	return !isCode || code >= http.StatusBadRequest
+}
+
+// Run - run hooks subcommand, returns exit code
+func Run(args []string, out io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(out, usage)
+		return 2
+	}
+	var handle func(*gitlab.Client, *gitlab.Project, string, string, io.Writer) bool
+	switch args[0] {
+	case "sync":
+		handle = syncHook
+	case "check":
+		handle = checkHook
+	case "remove":
+		handle = removeHook
+	default:
+		fmt.Fprint(out, usage)
+		return 2
+	}
+	flags := flag.NewFlagSet("hooks "+args[0], flag.ContinueOnError)
+	flags.SetOutput(out)
+	hookURL := flags.String("url", "", "URL GitLab sends webhooks to")
+	projects := flags.String("projects", "", "comma separated project paths")
+	group := flags.String("group", "", "group whose projects including subgroups get webhook")
+	instanceName := flags.String("instance", config.DefaultInstance, "GitLab instance name")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if *hookURL == "" || (*projects == "" && *group == "") {
+		fmt.Fprint(out, usage)
+		return 2
+	}
+	var instance *config.GitlabInstance
+	instances := config.GetInstances()
+	for index := range instances {
+		if instances[index].Name == *instanceName {
+			instance = &instances[index]
+		}
+	}
+	if instance == nil {
+		fmt.Fprintf(out, "Unknown GitLab instance %s\n", *instanceName)
+		return 2
+	}
+	gitlabClient := config.InitGitlabClient(instance.Token, instance.URL)
+	var groups []string
+	if *group != "" {
+		groups = []string{*group}
+	}
+	targets, err := gitlabUserAPI.ListProjects(splitPaths(*projects), groups, gitlabClient)
+	if err != nil {
+		hooksLogger.Errorf("Can't list projects: %s", err.Error())
+		return 1
+	}
+	code := 0
+	for _, project := range targets {
+		if !handle(gitlabClient, project, *hookURL, instance.WebhookSecret, out) {
+			code = 1
+		}
+	}
+	return code
+}
+
+// splitPaths - comma separated project paths
+func splitPaths(projectPaths string) []string {
+	var paths []string
+	for _, projectPath := range strings.Split(projectPaths, ",") {
+		if projectPath = strings.TrimSpace(projectPath); projectPath != "" {
+			paths = append(paths, projectPath)
+		}
+	}
+	return paths
+}
+
+// findHook - bot webhook of project, nil if there is none
+func findHook(gitlabClient *gitlab.Client, projectID int, hookURL string) (*projectHook, error) {
+	request, err := gitlabClient.NewRequest(http.MethodGet, fmt.Sprintf("projects/%d/hooks", projectID), &gitlab.ListOptions{PerPage: 100}, nil)
+	if err != nil {
+		return nil, err
+	}
+	var projectHooks []*projectHook
+	if _, err := gitlabClient.Do(request, &projectHooks); err != nil {
+		return nil, err
+	}
+	for _, hook := range projectHooks {
+		if hook.URL == hookURL {
+			return hook, nil
+		}
+	}
+	return nil, nil
+}
+
+// wrongEvents - events bot needs but hook doesn't send
+func wrongEvents(hook *projectHook) []string {
+	var missing []string
+	required := []struct {
+		Name    string
+		Enabled bool
+	}{
+		{"issues", hook.IssuesEvents},
+		{"confidential issues", hook.ConfidentialIssuesEvents},
+		{"comments", hook.NoteEvents},
+		{"confidential comments", hook.ConfidentialNoteEvents},
+	}
+	for _, event := range required {
+		if !event.Enabled {
+			missing = append(missing, event.Name)
+		}
+	}
+	return missing
+}
+
+// syncHook - create bot webhook on project or update existing one
+func syncHook(gitlabClient *gitlab.Client, project *gitlab.Project, hookURL string, secret string, out io.Writer) bool {
+	hook, err := findHook(gitlabClient, project.ID, hookURL)
+	if err != nil {
+		hooksLogger.Errorf("Can't list webhooks of %s: %s", project.PathWithNamespace, err.Error())
+		return false
+	}
+	if hook == nil {
+		_, _, err = gitlabClient.Projects.AddProjectHook(project.ID, &gitlab.AddProjectHookOptions{
+			URL:                      gitlab.String(hookURL),
+			Token:                    gitlab.String(secret),
+			IssuesEvents:             gitlab.Bool(true),
+			ConfidentialIssuesEvents: gitlab.Bool(true),
+			NoteEvents:               gitlab.Bool(true),
+			ConfidentialNoteEvents:   gitlab.Bool(true),
+			EnableSSLVerification:    gitlab.Bool(true),
+		})
+	} else {
+		_, _, err = gitlabClient.Projects.EditProjectHook(project.ID, hook.ID, &gitlab.EditProjectHookOptions{
+			URL:                      gitlab.String(hookURL),
+			Token:                    gitlab.String(secret),
+			IssuesEvents:             gitlab.Bool(true),
+			ConfidentialIssuesEvents: gitlab.Bool(true),
+			NoteEvents:               gitlab.Bool(true),
+			ConfidentialNoteEvents:   gitlab.Bool(true),
+			EnableSSLVerification:    gitlab.Bool(true),
+		})
+	}
+	if err != nil {
+		hooksLogger.Errorf("Can't save webhook of %s: %s", project.PathWithNamespace, err.Error())
+		return false
+	}
+	if hook == nil {
+		fmt.Fprintf(out, "%s: created\n", project.PathWithNamespace)
+	} else {
+		fmt.Fprintf(out, "%s: updated\n", project.PathWithNamespace)
+	}
+	return true
+}
+
+// checkHook - report drift of project webhook. Returns false if there is any
+func checkHook(gitlabClient *gitlab.Client, project *gitlab.Project, hookURL string, _ string, out io.Writer) bool {
+	hook, err := findHook(gitlabClient, project.ID, hookURL)
+	if err != nil {
+		hooksLogger.Errorf("Can't list webhooks of %s: %s", project.PathWithNamespace, err.Error())
+		return false
+	}
+	if hook == nil {
+		fmt.Fprintf(out, "%s: missing\n", project.PathWithNamespace)
+		return false
+	}
+	var problems []string
+	if hook.AlertStatus != "" && hook.AlertStatus != "executable" {
+		problem := strings.ReplaceAll(hook.AlertStatus, "_", " ")
+		if hook.DisabledUntil != nil {
+			problem += " until " + hook.DisabledUntil.Format(time.RFC3339)
+		}
+		problems = append(problems, problem)
+	}
+	if missing := wrongEvents(hook); len(missing) > 0 {
+		problems = append(problems, "no "+strings.Join(missing, ", ")+" events")
+	}
+	failures, err := recentFailures(gitlabClient, project.ID, hook.ID)
+	if err != nil {
+		hooksLogger.Warnf("Can't get recent deliveries of %s webhook: %s", project.PathWithNamespace, err.Error())
+	} else if failures > 0 {
+		problems = append(problems, fmt.Sprintf("%d of last %d deliveries failed", failures, recentEventsLimit))
+	}
+	if len(problems) == 0 {
+		fmt.Fprintf(out, "%s: ok\n", project.PathWithNamespace)
+		return true
+	}
+	fmt.Fprintf(out, "%s: %s\n", project.PathWithNamespace, strings.Join(problems, "; "))
+	return false
+}
+
+// recentFailures - count failed deliveries among recent ones. Zero if GitLab doesn't keep them
+func recentFailures(gitlabClient *gitlab.Client, projectID int, hookID int) (int, error) {
+	request, err := gitlabClient.NewRequest(http.MethodGet, fmt.Sprintf("projects/%d/hooks/%d/events", projectID, hookID), &gitlab.ListOptions{PerPage: recentEventsLimit}, nil)
+	if err != nil {
+		return 0, err
+	}
+	var events []hookEvent
+	response, err := gitlabClient.Do(request, &events)
+	if err != nil {
+		// Webhook events API appeared in GitLab 17
+		if response != nil && response.StatusCode == http.StatusNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	failures := 0
+	for _, event := range events {
+		if event.failed() {
+			failures++
+		}
+	}
+	return failures, nil
+}
+
+// removeHook - delete bot webhook from project
+func removeHook(gitlabClient *gitlab.Client, project *gitlab.Project, hookURL string, _ string, out io.Writer) bool {
+	hook, err := findHook(gitlabClient, project.ID, hookURL)
+	if err != nil {
+		hooksLogger.Errorf("Can't list webhooks of %s: %s", project.PathWithNamespace, err.Error())
+		return false
+	}
+	if hook == nil {
+		fmt.Fprintf(out, "%s: no webhook\n", project.PathWithNamespace)
+		return true
+	}
+	if _, err := gitlabClient.Projects.DeleteProjectHook(project.ID, hook.ID); err != nil {
+		hooksLogger.Errorf("Can't remove webhook of %s: %s", project.PathWithNamespace, err.Error())
+		return false
+	}
+	fmt.Fprintf(out, "%s: removed\n", project.PathWithNamespace)
+	return true
+}
@@ -13,10 +13,11 @@ var parserLogger = log.WithFields(log.Fields{
 })
 
 type issueType struct {
-	Kind string `json:"object_kind"`
+	Kind      string `json:"object_kind"`
+	EventName string `json:"event_name"`
 }
 
-// ParseBody - parse http body with issue or issue comment
+// ParseBody - parse http body with issue, issue comment or system hook user event
 func ParseBody(body []byte) (issue.Issue, error) {
 	issueKind := &issueType{}
 	parserLogger.Debugf("Body: %s", string(body))
@@ -25,21 +26,36 @@ func ParseBody(body []byte) (issue.Issue, error) {
 	}
 
 	generatedIssue := &issue.Issue{}
+	if issueKind.Kind == "" && issue.IsUserEvent(issueKind.EventName) {
+		systemHook := &issue.SystemHookSpec{}
+		if err := json.Unmarshal(body, systemHook); err != nil {
+			return issue.Issue{}, err
+		}
+		generatedIssue.SystemHook = systemHook
+		return *generatedIssue, nil
+	}
 	switch issueKind.Kind {
-	case "issue":
+	case issue.KindIssue, issue.KindConfidentialIssue:
 		issueBody := &issue.BodySpec{}
 		if err := json.Unmarshal(body, issueBody); err != nil {
 			return issue.Issue{}, err
 		}
+		if issueKind.Kind == issue.KindConfidentialIssue {
+			issueBody.ObjectAttributes.Confidential = true
+		}
 		generatedIssue.IssueBody = issueBody
-	case "note":
+	case issue.KindNote, issue.KindConfidentialNote:
 		issueNote := &issue.NoteSpec{}
 		if err := json.Unmarshal(body, issueNote); err != nil {
 			return issue.Issue{}, err
 		}
+		// Older GitLab versions call internal notes confidential
+		if issueKind.Kind == issue.KindConfidentialNote || issueNote.ObjectAttributes.Confidential {
+			issueNote.ObjectAttributes.Internal = true
+		}
 		generatedIssue.IssueNote = issueNote
 	default:
-		return issue.Issue{}, errors.New("Not issue/note")
+		return issue.Issue{}, errors.New("Not issue/note/system hook user event")
 	}
 	return *generatedIssue, nil
 }
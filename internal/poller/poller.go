@@ -0,0 +1,315 @@
+package poller
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	config "github.com/aberestyak/gitlab-issue-bot/internal/config"
+	gitlabUserAPI "github.com/aberestyak/gitlab-issue-bot/internal/gitlabAPI"
+	storage "github.com/aberestyak/gitlab-issue-bot/internal/storage"
+	issue "github.com/aberestyak/gitlab-issue-bot/pkg/model"
+	log "github.com/sirupsen/logrus"
+	"github.com/xanzy/go-gitlab"
+)
+
+var pollerLogger = log.WithFields(log.Fields{
+	"component": "Poller",
+})
+
+// issueActions - webhook actions of issue event actions. Other actions don't tell what
+// was changed and are skipped
+var issueActions = map[string]string{
+	"opened":   "open",
+	"closed":   "close",
+	"reopened": "reopen",
+}
+
+// visibilityLevels - webhook visibility levels of project visibilities
+var visibilityLevels = map[gitlab.VisibilityValue]int{
+	gitlab.PrivateVisibility:  0,
+	gitlab.InternalVisibility: 10,
+	gitlab.PublicVisibility:   20,
+}
+
+// Poller - reads project events from GitLab API for projects without webhooks
+type Poller struct {
+	gitlab   *gitlab.Client
+	store    *storage.Store
+	notify   func(issue.Issue)
+	projects []string
+	groups   []string
+	interval time.Duration
+}
+
+// issueNote - issue comment with fields unknown to go-gitlab
+type issueNote struct {
+	gitlab.Note
+	Internal     bool `json:"internal"`
+	Confidential bool `json:"confidential"`
+}
+
+// NewPoller - create poller of projects and groups from configuration
+func NewPoller(botConfig config.BotConfig, gitlabClient *gitlab.Client, store *storage.Store, notify func(issue.Issue)) *Poller {
+	return &Poller{
+		gitlab:   gitlabClient,
+		store:    store,
+		notify:   notify,
+		projects: botConfig.PollProjects,
+		groups:   botConfig.PollGroups,
+		interval: botConfig.PollInterval,
+	}
+}
+
+// Start - poll events periodically. Blocks forever
+func (poller *Poller) Start() {
+	ticker := time.NewTicker(poller.interval)
+	defer ticker.Stop()
+	for {
+		poller.poll()
+		<-ticker.C
+	}
+}
+
+// poll - process new events of all polled projects
+func (poller *Poller) poll() {
+	projects, err := gitlabUserAPI.ListProjects(poller.projects, poller.groups, poller.gitlab)
+	if err != nil {
+		pollerLogger.Errorf("Can't list polled projects: %s", err.Error())
+		return
+	}
+	for _, project := range projects {
+		if err := poller.pollProject(project); err != nil {
+			pollerLogger.Errorf("Can't poll events of %s: %s", project.PathWithNamespace, err.Error())
+		}
+	}
+}
+
+// pollProject - convert new events of project into issue events. Cursor moves after every
+// event, so failed event is retried on next poll and processed ones are not repeated
+func (poller *Poller) pollProject(project *gitlab.Project) error {
+	cursor, found := poller.store.GetPollCursor(project.ID)
+	if !found {
+		return poller.startFromLatest(project)
+	}
+	events, err := poller.newEvents(project.ID, cursor)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		issueEvent, err := poller.convert(project, event)
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+		if err != nil {
+			pollerLogger.Warnf("Event %d of %s refers to deleted object, skip it", event.ID, project.PathWithNamespace)
+		} else if issueEvent != nil {
+			poller.notify(*issueEvent)
+		}
+		cursor = storage.PollCursor{EventID: event.ID, At: eventTime(event)}
+		if err := poller.store.SavePollCursor(project.ID, cursor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startFromLatest - remember latest event of newly polled project, history is not sent
+func (poller *Poller) startFromLatest(project *gitlab.Project) error {
+	events, _, err := poller.gitlab.Events.ListProjectVisibleEvents(project.ID, &gitlab.ListContributionEventsOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 1},
+	})
+	if err != nil {
+		return err
+	}
+	cursor := storage.PollCursor{At: time.Now()}
+	if len(events) > 0 {
+		cursor = storage.PollCursor{EventID: events[0].ID, At: eventTime(events[0])}
+	}
+	pollerLogger.Infof("Start polling %s after event %d", project.PathWithNamespace, cursor.EventID)
+	return poller.store.SavePollCursor(project.ID, cursor)
+}
+
+// newEvents - project events after cursor, oldest first
+func (poller *Poller) newEvents(projectID int, cursor storage.PollCursor) ([]*gitlab.ContributionEvent, error) {
+	// API filters by dates only and excludes the day of after
+	after := gitlab.ISOTime(cursor.At.AddDate(0, 0, -1))
+	options := &gitlab.ListContributionEventsOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 100, Page: 1},
+		After:       &after,
+	}
+	seen := map[int]bool{}
+	var events []*gitlab.ContributionEvent
+	for {
+		page, response, err := poller.gitlab.Events.ListProjectVisibleEvents(projectID, options)
+		if err != nil {
+			return nil, err
+		}
+		reached := false
+		for _, event := range page {
+			if event.ID <= cursor.EventID {
+				reached = true
+				continue
+			}
+			// Pages shift when new events arrive
+			if !seen[event.ID] {
+				seen[event.ID] = true
+				events = append(events, event)
+			}
+		}
+		if reached || response.NextPage == 0 {
+			break
+		}
+		options.Page = response.NextPage
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ID < events[j].ID
+	})
+	return events, nil
+}
+
+// convert - make webhook-like issue event from project event. Nil if event is not about issue
+func (poller *Poller) convert(project *gitlab.Project, event *gitlab.ContributionEvent) (*issue.Issue, error) {
+	switch {
+	case event.TargetType == "Issue":
+		action, found := issueActions[event.ActionName]
+		if !found {
+			pollerLogger.Debugf("Skip %s event %d of issue #%d in %s", event.ActionName, event.ID, event.TargetIID, project.PathWithNamespace)
+			return nil, nil
+		}
+		attributes, err := poller.issueAttributes(project.ID, event.TargetIID)
+		if err != nil {
+			return nil, err
+		}
+		attributes.Action = action
+		attributes.UpdatedBy = event.AuthorID
+		kind := issue.KindIssue
+		if attributes.Confidential {
+			kind = issue.KindConfidentialIssue
+		}
+		return &issue.Issue{
+			Instance: config.DefaultInstance,
+			IssueBody: &issue.BodySpec{
+				Kind:             kind,
+				User:             issue.Author{Name: event.Author.Name, ID: event.AuthorID},
+				Project:          projectOf(project),
+				ObjectAttributes: attributes,
+			},
+		}, nil
+	case event.Note != nil && event.Note.NoteableType == "Issue":
+		attributes, err := poller.issueAttributes(project.ID, event.Note.NoteableIID)
+		if err != nil {
+			return nil, err
+		}
+		note, err := poller.issueNote(project.ID, event.Note.NoteableIID, event.Note.ID)
+		if err != nil {
+			return nil, err
+		}
+		if note.System {
+			return nil, nil
+		}
+		discussionID := ""
+		// Events API doesn't tell thread of comment, replies need it to stay in thread
+		if note.Type == issue.DiscussionNoteType {
+			if discussionID, err = poller.discussionID(project.ID, event.Note.NoteableIID, note.ID); err != nil {
+				return nil, err
+			}
+		}
+		kind := issue.KindNote
+		if note.Internal || note.Confidential {
+			kind = issue.KindConfidentialNote
+		}
+		return &issue.Issue{
+			Instance: config.DefaultInstance,
+			IssueNote: &issue.NoteSpec{
+				Kind:    kind,
+				User:    issue.Author{Name: note.Author.Name, ID: note.Author.ID},
+				Project: projectOf(project),
+				ObjectAttributes: issue.NotesAttibutes{
+					ID:           note.ID,
+					Note:         note.Body,
+					Description:  note.Body,
+					URL:          fmt.Sprintf("%s#note_%d", attributes.URL, note.ID),
+					Type:         string(note.Type),
+					DiscussionID: discussionID,
+					Internal:     note.Internal || note.Confidential,
+				},
+				Issue: attributes,
+			},
+		}, nil
+	}
+	return nil, nil
+}
+
+// issueAttributes - current attributes of issue
+func (poller *Poller) issueAttributes(projectID int, issueIID int) (issue.Attibutes, error) {
+	gitlabIssue, _, err := poller.gitlab.Issues.GetIssue(projectID, issueIID)
+	if err != nil {
+		return issue.Attibutes{}, err
+	}
+	attributes := issue.AttributesFromGitlab(gitlabIssue)
+	// Names are resolved from IDs like for webhooks
+	attributes.AssigneeNames = nil
+	return attributes, nil
+}
+
+// issueNote - issue comment with internal flag
+func (poller *Poller) issueNote(projectID int, issueIID int, noteID int) (*issueNote, error) {
+	request, err := poller.gitlab.NewRequest(http.MethodGet, fmt.Sprintf("projects/%d/issues/%d/notes/%d", projectID, issueIID, noteID), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	note := &issueNote{}
+	if _, err := poller.gitlab.Do(request, note); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// discussionID - ID of issue thread containing note. Empty if thread is not found
+func (poller *Poller) discussionID(projectID int, issueIID int, noteID int) (string, error) {
+	options := &gitlab.ListIssueDiscussionsOptions{PerPage: 100, Page: 1}
+	for {
+		discussions, response, err := poller.gitlab.Discussions.ListIssueDiscussions(projectID, issueIID, options)
+		if err != nil {
+			return "", err
+		}
+		for _, discussion := range discussions {
+			for _, note := range discussion.Notes {
+				if note.ID == noteID {
+					return discussion.ID, nil
+				}
+			}
+		}
+		if response.NextPage == 0 {
+			return "", nil
+		}
+		options.Page = response.NextPage
+	}
+}
+
+// projectOf - webhook project of API project
+func projectOf(project *gitlab.Project) issue.Project {
+	return issue.Project{
+		ID:                project.ID,
+		Name:              project.Name,
+		PathWithNamespace: project.PathWithNamespace,
+		WebURL:            project.WebURL,
+		VisibilityLevel:   visibilityLevels[project.Visibility],
+	}
+}
+
+// eventTime - creation time of event, now if GitLab didn't send it
+func eventTime(event *gitlab.ContributionEvent) time.Time {
+	if event.CreatedAt == nil {
+		return time.Now()
+	}
+	return *event.CreatedAt
+}
+
+// isNotFound - check API error means object was deleted
+func isNotFound(err error) bool {
+	errorResponse, isResponse := err.(*gitlab.ErrorResponse)
+	return isResponse && errorResponse.Response != nil && errorResponse.Response.StatusCode == http.StatusNotFound
+}
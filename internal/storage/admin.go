@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"sort"
+	"time"
+)
+
+// UnmappedUser - gitlab user who was a recipient but has no Telegram
+type UnmappedUser struct {
+	Name     string    `json:"name"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// RecordUnmapped - remember recipient without Telegram
+func (store *Store) RecordUnmapped(name string, now time.Time) error {
+	return store.Update(func(data *Data) error {
+		data.Unmapped[name] = now
+		return nil
+	})
+}
+
+// ForgetUnmapped - recipient got Telegram
+func (store *Store) ForgetUnmapped(name string) error {
+	found := false
+	store.View(func(data *Data) {
+		_, found = data.Unmapped[name]
+	})
+	if !found {
+		return nil
+	}
+	return store.Update(func(data *Data) error {
+		delete(data.Unmapped, name)
+		return nil
+	})
+}
+
+// UnmappedUsers - recipients without Telegram, recently seen first
+func (store *Store) UnmappedUsers() []UnmappedUser {
+	var users []UnmappedUser
+	store.View(func(data *Data) {
+		for name, lastSeen := range data.Unmapped {
+			users = append(users, UnmappedUser{Name: name, LastSeen: lastSeen})
+		}
+	})
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].LastSeen.After(users[j].LastSeen)
+	})
+	return users
+}
+
+// LinkedUsers - users linked with gitlab accounts ordered by gitlab username
+func (store *Store) LinkedUsers() []User {
+	var users []User
+	store.View(func(data *Data) {
+		for _, user := range data.Users {
+			if user.GitlabID != 0 || len(user.Accounts) > 0 {
+				users = append(users, user.clone())
+			}
+		}
+	})
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].GitlabUsername < users[j].GitlabUsername
+	})
+	return users
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// Alert - critical notification waiting for acknowledgement
+type Alert struct {
+	ChatID    int64     `json:"chat_id"`
+	MessageID int       `json:"message_id"`
+	IssueKey  string    `json:"issue_key"`
+	IssueURL  string    `json:"issue_url"`
+	Pinned    bool      `json:"pinned,omitempty"`
+	NextPing  time.Time `json:"next_ping"`
+	Pings     int       `json:"pings,omitempty"`
+}
+
+func (alert Alert) due(now time.Time, limit int) bool {
+	return alert.Pings < limit && !now.Before(alert.NextPing)
+}
+
+func alertKey(chatID int64, messageID int) string {
+	return fmt.Sprintf("%d:%d", chatID, messageID)
+}
+
+// AddAlert - remember notification to re-ping until acknowledged
+func (store *Store) AddAlert(alert Alert) error {
+	return store.Update(func(data *Data) error {
+		data.Alerts[alertKey(alert.ChatID, alert.MessageID)] = alert
+		return nil
+	})
+}
+
+// HasAlert - check message is alert waiting for acknowledgement
+func (store *Store) HasAlert(chatID int64, messageID int) bool {
+	var found bool
+	store.View(func(data *Data) {
+		_, found = data.Alerts[alertKey(chatID, messageID)]
+	})
+	return found
+}
+
+// TakeAlerts - remove and return alerts about issue, in chat or in all chats if chatID is zero
+func (store *Store) TakeAlerts(chatID int64, issueKey string) ([]Alert, error) {
+	var alerts []Alert
+	err := store.Update(func(data *Data) error {
+		for key, alert := range data.Alerts {
+			if alert.IssueKey == issueKey && (chatID == 0 || alert.ChatID == chatID) {
+				alerts = append(alerts, alert)
+				delete(data.Alerts, key)
+			}
+		}
+		return nil
+	})
+	return alerts, err
+}
+
+// DueAlerts - alerts to re-ping now. Their next ping is scheduled after interval. Alerts pinged
+// limit times are not re-pinged anymore but are kept, so they are still unpinned on acknowledge
+func (store *Store) DueAlerts(now time.Time, interval time.Duration, limit int) ([]Alert, error) {
+	due := false
+	store.View(func(data *Data) {
+		for _, alert := range data.Alerts {
+			due = due || alert.due(now, limit)
+		}
+	})
+	if !due {
+		return nil, nil
+	}
+	var alerts []Alert
+	err := store.Update(func(data *Data) error {
+		for key, alert := range data.Alerts {
+			if !alert.due(now, limit) {
+				continue
+			}
+			alert.Pings++
+			alert.NextPing = now.Add(interval)
+			data.Alerts[key] = alert
+			alerts = append(alerts, alert)
+		}
+		return nil
+	})
+	return alerts, err
+}
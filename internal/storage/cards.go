@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// Card - message with issue summary which is edited on every event
+type Card struct {
+	MessageID         int       `json:"message_id"`
+	LastComment       string    `json:"last_comment,omitempty"`
+	LastCommentAuthor string    `json:"last_comment_author,omitempty"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+func cardKey(chatID int64, issueKey string) string {
+	return fmt.Sprintf("%d:%s", chatID, issueKey)
+}
+
+// GetCard - get issue card sent to chat
+func (store *Store) GetCard(chatID int64, issueKey string) (Card, bool) {
+	var card Card
+	var found bool
+	store.View(func(data *Data) {
+		card, found = data.Cards[cardKey(chatID, issueKey)]
+	})
+	return card, found
+}
+
+// SaveCard - remember issue card sent to chat
+func (store *Store) SaveCard(chatID int64, issueKey string, card Card) error {
+	return store.Update(func(data *Data) error {
+		for key, stored := range data.Cards {
+			if card.UpdatedAt.Sub(stored.UpdatedAt) > messageTTL {
+				delete(data.Cards, key)
+			}
+		}
+		data.Cards[cardKey(chatID, issueKey)] = card
+		return nil
+	})
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"time"
+
+	issue "github.com/aberestyak/gitlab-issue-bot/pkg/model"
+)
+
+// Digest intervals
+const (
+	DigestHourly = "hourly"
+	DigestDaily  = "daily"
+)
+
+// Digest - user's setting to receive events batched per interval
+type Digest struct {
+	Interval string    `json:"interval,omitempty"`
+	At       string    `json:"at,omitempty"`
+	LastSent time.Time `json:"last_sent,omitempty"`
+}
+
+// Enabled - check if user receives digests instead of separate notifications
+func (digest Digest) Enabled() bool {
+	return digest.Interval == DigestHourly || digest.Interval == DigestDaily
+}
+
+// Due - check if it's time to send next digest. Daily digest is sent at "At" of user's local time
+func (digest Digest) Due(now time.Time, location *time.Location) bool {
+	switch digest.Interval {
+	case DigestHourly:
+		return now.Sub(digest.LastSent) >= time.Hour
+	case DigestDaily:
+		at, err := ParseClock(digest.At)
+		if err != nil {
+			at = 0
+		}
+		local := now.In(location)
+		scheduled := time.Date(local.Year(), local.Month(), local.Day(), at/60, at%60, 0, 0, location)
+		return !now.Before(scheduled) && digest.LastSent.Before(scheduled)
+	}
+	return false
+}
+
+// SetDigest - change user's digest setting
+func (store *Store) SetDigest(telegramID int, interval string, at string, now time.Time) error {
+	return store.Update(func(data *Data) error {
+		user := data.user(telegramID)
+		user.Digest.Interval = interval
+		user.Digest.At = at
+		if user.Digest.LastSent.IsZero() {
+			user.Digest.LastSent = now
+		}
+		return nil
+	})
+}
+
+// AddDigestEvents - add events to user's next digest
+func (store *Store) AddDigestEvents(telegramID int, events ...issue.SummaryEvent) error {
+	return store.Update(func(data *Data) error {
+		user := data.user(telegramID)
+		if !user.Receives() {
+			return nil
+		}
+		user.DigestEvents = append(user.DigestEvents, events...)
+		return nil
+	})
+}
+
+// UsersWithDigest - IDs of users receiving digests
+func (store *Store) UsersWithDigest() []int {
+	var telegramIDs []int
+	store.View(func(data *Data) {
+		for telegramID, user := range data.Users {
+			if user.Digest.Enabled() {
+				telegramIDs = append(telegramIDs, telegramID)
+			}
+		}
+	})
+	return telegramIDs
+}
+
+// TakeDigestEvents - get and forget events of user's digest and remember when digest was sent
+func (store *Store) TakeDigestEvents(telegramID int, now time.Time) ([]issue.SummaryEvent, error) {
+	var events []issue.SummaryEvent
+	err := store.Update(func(data *Data) error {
+		user := data.user(telegramID)
+		events, user.DigestEvents = user.DigestEvents, nil
+		user.Digest.LastSent = now
+		return nil
+	})
+	return events, err
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestDigestDue(t *testing.T) {
+	location := func(name string) *time.Location {
+		loaded, err := time.LoadLocation(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return loaded
+	}
+	moscow := location("Europe/Moscow")
+	losAngeles := location("America/Los_Angeles")
+	tokyo := location("Asia/Tokyo")
+	berlin := location("Europe/Berlin")
+	utc := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2022, month, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name     string
+		digest   Digest
+		now      time.Time
+		location *time.Location
+		want     bool
+	}{
+		// Moscow is UTC+3
+		{"before local time", Digest{Interval: DigestDaily, At: "09:00", LastSent: utc(1, 11, 6, 0)}, utc(1, 12, 5, 59), moscow, false},
+		{"at local time", Digest{Interval: DigestDaily, At: "09:00", LastSent: utc(1, 11, 6, 0)}, utc(1, 12, 6, 0), moscow, true},
+		{"already sent today", Digest{Interval: DigestDaily, At: "09:00", LastSent: utc(1, 12, 6, 0)}, utc(1, 12, 20, 0), moscow, false},
+		// Los Angeles is UTC-8, its evening is next day in UTC
+		{"local day behind UTC", Digest{Interval: DigestDaily, At: "09:00", LastSent: utc(1, 11, 17, 0)}, utc(1, 13, 1, 0), losAngeles, true},
+		{"sent today while UTC day changed", Digest{Interval: DigestDaily, At: "09:00", LastSent: utc(1, 12, 17, 0)}, utc(1, 13, 1, 0), losAngeles, false},
+		// Tokyo is UTC+9, its morning is previous day in UTC
+		{"local day ahead of UTC", Digest{Interval: DigestDaily, At: "08:00", LastSent: utc(1, 11, 23, 0)}, utc(1, 12, 23, 30), tokyo, true},
+		{"before local time on next local day", Digest{Interval: DigestDaily, At: "08:00", LastSent: utc(1, 11, 23, 0)}, utc(1, 12, 22, 30), tokyo, false},
+		// Berlin switches to UTC+2 on March 27
+		{"before local time on summer time switch", Digest{Interval: DigestDaily, At: "09:00", LastSent: utc(3, 26, 8, 0)}, utc(3, 27, 6, 59), berlin, false},
+		{"at local time on summer time switch", Digest{Interval: DigestDaily, At: "09:00", LastSent: utc(3, 26, 8, 0)}, utc(3, 27, 7, 0), berlin, true},
+		{"wrong time means midnight", Digest{Interval: DigestDaily, At: "25:00", LastSent: utc(1, 11, 0, 0)}, utc(1, 12, 0, 0), time.UTC, true},
+		{"hour passed", Digest{Interval: DigestHourly, LastSent: utc(1, 12, 10, 0)}, utc(1, 12, 11, 0), tokyo, true},
+		{"hour not passed", Digest{Interval: DigestHourly, LastSent: utc(1, 12, 10, 0)}, utc(1, 12, 10, 59), tokyo, false},
+		{"disabled", Digest{LastSent: utc(1, 1, 0, 0)}, utc(1, 12, 10, 0), time.UTC, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.digest.Due(test.now, test.location); got != test.want {
+				t.Errorf("Due(%s) = %t, want %t", test.now.Format(time.RFC3339), got, test.want)
+			}
+		})
+	}
+}
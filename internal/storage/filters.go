@@ -0,0 +1,48 @@
+package storage
+
+import "fmt"
+
+// Filter modes
+const (
+	FilterInclude = "include"
+	FilterExclude = "exclude"
+)
+
+// Filter fields
+const (
+	FilterProject = "project"
+	FilterLabel   = "label"
+)
+
+// Filter - rule to include or exclude events by project path or label
+type Filter struct {
+	Mode    string `json:"mode"`
+	Field   string `json:"field"`
+	Pattern string `json:"pattern"`
+}
+
+// String - human readable filter
+func (filter Filter) String() string {
+	return fmt.Sprintf("%s %s %s", filter.Mode, filter.Field, filter.Pattern)
+}
+
+// AddFilter - add filter to user's filters
+func (store *Store) AddFilter(telegramID int, filter Filter) error {
+	return store.Update(func(data *Data) error {
+		user := data.user(telegramID)
+		user.Filters = append(user.Filters, filter)
+		return nil
+	})
+}
+
+// RemoveFilter - remove user's filter by index
+func (store *Store) RemoveFilter(telegramID int, index int) error {
+	return store.Update(func(data *Data) error {
+		user := data.user(telegramID)
+		if index < 0 || index >= len(user.Filters) {
+			return fmt.Errorf("there is no filter number %d", index+1)
+		}
+		user.Filters = append(user.Filters[:index], user.Filters[index+1:]...)
+		return nil
+	})
+}
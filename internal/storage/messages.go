@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// Messages older than this are forgotten, so replies to them are ignored
+const messageTTL = 30 * 24 * time.Hour
+
+// MessageRef - issue the telegram message was sent about
+type MessageRef struct {
+	Instance     string    `json:"instance,omitempty"`
+	ProjectID    int       `json:"project_id"`
+	IssueIID     int       `json:"issue_iid"`
+	IssueURL     string    `json:"issue_url"`
+	DiscussionID string    `json:"discussion_id,omitempty"`
+	SentAt       time.Time `json:"sent_at"`
+}
+
+func messageKey(chatID int64, messageID int) string {
+	return fmt.Sprintf("%d:%d", chatID, messageID)
+}
+
+// RememberMessage - remember which issue the message was sent about
+func (store *Store) RememberMessage(chatID int64, messageID int, ref MessageRef) error {
+	return store.Update(func(data *Data) error {
+		for key, stored := range data.Messages {
+			if ref.SentAt.Sub(stored.SentAt) > messageTTL {
+				delete(data.Messages, key)
+			}
+		}
+		data.Messages[messageKey(chatID, messageID)] = ref
+		return nil
+	})
+}
+
+// GetMessage - get issue the message was sent about
+func (store *Store) GetMessage(chatID int64, messageID int) (MessageRef, bool) {
+	var ref MessageRef
+	var found bool
+	store.View(func(data *Data) {
+		ref, found = data.Messages[messageKey(chatID, messageID)]
+	})
+	return ref, found
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mute - issue muted or snoozed by user
+type Mute struct {
+	Until    time.Time `json:"until,omitempty"`
+	IssueURL string    `json:"issue_url,omitempty"`
+}
+
+// Active - zero until mutes forever, otherwise issue is snoozed till until
+func (mute Mute) Active(now time.Time) bool {
+	return mute.Until.IsZero() || now.Before(mute.Until)
+}
+
+// MutedIssue - muted issue with its key
+type MutedIssue struct {
+	Mute
+	Instance  string
+	ProjectID int
+	IssueIID  int
+}
+
+// IssueKeyInstance - instance of issue key, empty for default instance
+func IssueKeyInstance(issueKey string) string {
+	if index := strings.LastIndex(issueKey, ":"); index >= 0 {
+		return issueKey[:index]
+	}
+	return ""
+}
+
+// ParseIssueKey - get project ID and issue IID from issue key
+func ParseIssueKey(issueKey string) (int, int, error) {
+	if index := strings.LastIndex(issueKey, ":"); index >= 0 {
+		issueKey = issueKey[index+1:]
+	}
+	parts := strings.Split(issueKey, "#")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("wrong issue key %s", issueKey)
+	}
+	projectID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	issueIID, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return projectID, issueIID, nil
+}
+
+// MuteIssue - stop notifications about issue for user
+func (store *Store) MuteIssue(telegramID int, issueKey string, mute Mute, now time.Time) error {
+	return store.Update(func(data *Data) error {
+		user := data.user(telegramID)
+		if user.Muted == nil {
+			user.Muted = map[string]Mute{}
+		}
+		for key, stored := range user.Muted {
+			if !stored.Active(now) {
+				delete(user.Muted, key)
+			}
+		}
+		user.Muted[issueKey] = mute
+		return nil
+	})
+}
+
+// UnmuteIssue - resume notifications about issue for user
+func (store *Store) UnmuteIssue(telegramID int, issueKey string) error {
+	return store.Update(func(data *Data) error {
+		delete(data.user(telegramID).Muted, issueKey)
+		return nil
+	})
+}
+
+// IsMuted - check if user muted or snoozed the issue
+func (store *Store) IsMuted(telegramID int, issueKey string, now time.Time) bool {
+	muted := false
+	store.View(func(data *Data) {
+		user, found := data.Users[telegramID]
+		if !found {
+			return
+		}
+		mute, found := user.Muted[issueKey]
+		muted = found && mute.Active(now)
+	})
+	return muted
+}
+
+// MutedIssues - user's active mutes ordered by issue key
+func (store *Store) MutedIssues(telegramID int, now time.Time) []MutedIssue {
+	var mutedIssues []MutedIssue
+	store.View(func(data *Data) {
+		user, found := data.Users[telegramID]
+		if !found {
+			return
+		}
+		for key, mute := range user.Muted {
+			if !mute.Active(now) {
+				continue
+			}
+			projectID, issueIID, err := ParseIssueKey(key)
+			if err != nil {
+				continue
+			}
+			mutedIssues = append(mutedIssues, MutedIssue{Mute: mute, Instance: IssueKeyInstance(key), ProjectID: projectID, IssueIID: issueIID})
+		}
+	})
+	sort.Slice(mutedIssues, func(i, j int) bool {
+		if mutedIssues[i].Instance != mutedIssues[j].Instance {
+			return mutedIssues[i].Instance < mutedIssues[j].Instance
+		}
+		if mutedIssues[i].ProjectID != mutedIssues[j].ProjectID {
+			return mutedIssues[i].ProjectID < mutedIssues[j].ProjectID
+		}
+		return mutedIssues[i].IssueIID < mutedIssues[j].IssueIID
+	})
+	return mutedIssues
+}
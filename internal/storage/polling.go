@@ -0,0 +1,27 @@
+package storage
+
+import "time"
+
+// PollCursor - latest event of project processed by events poller
+type PollCursor struct {
+	EventID int       `json:"event_id"`
+	At      time.Time `json:"at"`
+}
+
+// GetPollCursor - poller position in project, not found if project was never polled
+func (store *Store) GetPollCursor(projectID int) (PollCursor, bool) {
+	var cursor PollCursor
+	found := false
+	store.View(func(data *Data) {
+		cursor, found = data.PollCursors[projectID]
+	})
+	return cursor, found
+}
+
+// SavePollCursor - remember poller position in project
+func (store *Store) SavePollCursor(projectID int, cursor PollCursor) error {
+	return store.Update(func(data *Data) error {
+		data.PollCursors[projectID] = cursor
+		return nil
+	})
+}
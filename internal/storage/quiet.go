@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	issue "github.com/aberestyak/gitlab-issue-bot/pkg/model"
+)
+
+// QuietHours - time window when user's notifications are held
+type QuietHours struct {
+	TimeZone string `json:"time_zone,omitempty"`
+	Start    string `json:"start,omitempty"`
+	End      string `json:"end,omitempty"`
+	Weekends bool   `json:"weekends,omitempty"`
+}
+
+// Location - user's time zone, UTC if not set
+func (quiet QuietHours) Location() *time.Location {
+	if quiet.TimeZone == "" {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(quiet.TimeZone)
+	if err != nil {
+		storageLogger.Errorf("Can't load time zone %s: %s", quiet.TimeZone, err.Error())
+		return time.UTC
+	}
+	return location
+}
+
+// Active - check if moment is inside quiet hours
+func (quiet QuietHours) Active(now time.Time) bool {
+	local := now.In(quiet.Location())
+	if quiet.Weekends && (local.Weekday() == time.Saturday || local.Weekday() == time.Sunday) {
+		return true
+	}
+	start, startErr := ParseClock(quiet.Start)
+	end, endErr := ParseClock(quiet.End)
+	if startErr != nil || endErr != nil || start == end {
+		return false
+	}
+	minutes := local.Hour()*60 + local.Minute()
+	if start < end {
+		return minutes >= start && minutes < end
+	}
+	return minutes >= start || minutes < end
+}
+
+// ParseClock - parse "HH:MM" into minutes since midnight
+func ParseClock(clock string) (int, error) {
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("wrong time %s, use HH:MM", clock)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// SetQuietHours - change user's quiet hours
+func (store *Store) SetQuietHours(telegramID int, fn func(quiet *QuietHours)) (QuietHours, error) {
+	var quiet QuietHours
+	err := store.Update(func(data *Data) error {
+		user := data.user(telegramID)
+		fn(&user.Quiet)
+		quiet = user.Quiet
+		return nil
+	})
+	return quiet, err
+}
+
+// HoldEvents - postpone events to be sent to user later in summary
+func (store *Store) HoldEvents(telegramID int, events ...issue.SummaryEvent) error {
+	return store.Update(func(data *Data) error {
+		user := data.user(telegramID)
+		// Summary failed because user blocked the bot isn't kept
+		if !user.Receives() {
+			return nil
+		}
+		user.Held = append(user.Held, events...)
+		return nil
+	})
+}
+
+// UsersWithHeldEvents - IDs of users having postponed events
+func (store *Store) UsersWithHeldEvents() []int {
+	var telegramIDs []int
+	store.View(func(data *Data) {
+		for telegramID, user := range data.Users {
+			if len(user.Held) > 0 {
+				telegramIDs = append(telegramIDs, telegramID)
+			}
+		}
+	})
+	return telegramIDs
+}
+
+// TakeHeldEvents - get and forget user's postponed events
+func (store *Store) TakeHeldEvents(telegramID int) ([]issue.SummaryEvent, error) {
+	var events []issue.SummaryEvent
+	err := store.Update(func(data *Data) error {
+		user := data.user(telegramID)
+		events, user.Held = user.Held, nil
+		return nil
+	})
+	return events, err
+}
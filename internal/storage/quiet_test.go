@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestQuietHoursActive(t *testing.T) {
+	overnight := QuietHours{TimeZone: "Europe/Berlin", Start: "22:00", End: "07:00"}
+	daytime := QuietHours{TimeZone: "Europe/Berlin", Start: "12:00", End: "13:30"}
+	weekends := QuietHours{TimeZone: "Asia/Tokyo", Weekends: true}
+	tests := []struct {
+		name  string
+		quiet QuietHours
+		now   time.Time
+		want  bool
+	}{
+		// Berlin is UTC+1 in winter
+		{"before overnight window", overnight, time.Date(2022, 1, 12, 20, 59, 0, 0, time.UTC), false},
+		{"overnight window starts", overnight, time.Date(2022, 1, 12, 21, 0, 0, 0, time.UTC), true},
+		{"before midnight", overnight, time.Date(2022, 1, 12, 22, 59, 0, 0, time.UTC), true},
+		{"after midnight", overnight, time.Date(2022, 1, 12, 23, 30, 0, 0, time.UTC), true},
+		{"early morning", overnight, time.Date(2022, 1, 13, 5, 59, 0, 0, time.UTC), true},
+		{"overnight window ends", overnight, time.Date(2022, 1, 13, 6, 0, 0, 0, time.UTC), false},
+		{"overnight window in summer time", overnight, time.Date(2022, 7, 13, 4, 59, 0, 0, time.UTC), true},
+		{"after overnight window in summer time", overnight, time.Date(2022, 7, 13, 5, 0, 0, 0, time.UTC), false},
+		{"inside daytime window", daytime, time.Date(2022, 1, 12, 11, 0, 0, 0, time.UTC), true},
+		{"after daytime window", daytime, time.Date(2022, 1, 12, 12, 30, 0, 0, time.UTC), false},
+		{"empty window", QuietHours{Start: "10:00", End: "10:00"}, time.Date(2022, 1, 12, 10, 0, 0, 0, time.UTC), false},
+		{"not configured", QuietHours{}, time.Date(2022, 1, 12, 10, 0, 0, 0, time.UTC), false},
+		// Friday evening in UTC is Saturday in Tokyo
+		{"weekend in user's time zone", weekends, time.Date(2022, 1, 14, 16, 0, 0, 0, time.UTC), true},
+		{"weekday in user's time zone", weekends, time.Date(2022, 1, 16, 16, 0, 0, 0, time.UTC), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.quiet.Active(test.now); got != test.want {
+				t.Errorf("Active(%s) = %t, want %t", test.now.Format(time.RFC3339), got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package storage
+
+// recentProjectsLimit - how many recently used projects are remembered per user
+const recentProjectsLimit = 5
+
+// AddRecentProject - move project to the top of user's recently used projects
+func (store *Store) AddRecentProject(telegramID int, projectPath string) error {
+	return store.Update(func(data *Data) error {
+		user := data.user(telegramID)
+		recentProjects := []string{projectPath}
+		for _, recentProject := range user.RecentProjects {
+			if recentProject != projectPath && len(recentProjects) < recentProjectsLimit {
+				recentProjects = append(recentProjects, recentProject)
+			}
+		}
+		user.RecentProjects = recentProjects
+		return nil
+	})
+}
@@ -0,0 +1,44 @@
+package storage
+
+import "fmt"
+
+// Route - deliver events of projects matching pattern to group chat or channel
+type Route struct {
+	Pattern string `json:"pattern"`
+	ChatID  int64  `json:"chat_id"`
+}
+
+// AddRoute - add route unless chat already has it
+func (store *Store) AddRoute(route Route) error {
+	return store.Update(func(data *Data) error {
+		for _, existing := range data.Routes {
+			if existing == route {
+				return fmt.Errorf("route %s already exists", route.Pattern)
+			}
+		}
+		data.Routes = append(data.Routes, route)
+		return nil
+	})
+}
+
+// RemoveRoute - remove route of chat
+func (store *Store) RemoveRoute(route Route) error {
+	return store.Update(func(data *Data) error {
+		for index, existing := range data.Routes {
+			if existing == route {
+				data.Routes = append(data.Routes[:index], data.Routes[index+1:]...)
+				return nil
+			}
+		}
+		return fmt.Errorf("route %s not found", route.Pattern)
+	})
+}
+
+// Routes - copy of all routes added from Telegram
+func (store *Store) Routes() []Route {
+	var routes []Route
+	store.View(func(data *Data) {
+		routes = append(routes, data.Routes...)
+	})
+	return routes
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var storageLogger = log.WithFields(log.Fields{
+	"component": "Storage",
+})
+
+// Store - bot state persisted in JSON file
+type Store struct {
+	mu   sync.RWMutex
+	path string
+	data Data
+}
+
+// Data - everything bot has to remember between restarts
+type Data struct {
+	Users       map[int]*User         `json:"users"`
+	Messages    map[string]MessageRef `json:"messages"`
+	Threads     map[string]Thread     `json:"threads"`
+	Cards       map[string]Card       `json:"cards"`
+	Routes      []Route               `json:"routes,omitempty"`
+	TopicModes  map[int64]string      `json:"topic_modes"`
+	Topics      map[string]int        `json:"topics"`
+	Alerts      map[string]Alert      `json:"alerts"`
+	Unmapped    map[string]time.Time  `json:"unmapped"`
+	PollCursors map[int]PollCursor    `json:"poll_cursors"`
+}
+
+// Open - load state from file. Missing file means empty state
+func Open(path string) (*Store, error) {
+	store := &Store{path: path}
+	content, err := ioutil.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if len(content) > 0 {
+		if err := json.Unmarshal(content, &store.data); err != nil {
+			return nil, err
+		}
+	} else {
+		storageLogger.Infof("State file %s not found, starting with empty state", path)
+	}
+	store.data.init()
+	return store, nil
+}
+
+// View - read state under lock
+func (store *Store) View(fn func(data *Data)) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	fn(&store.data)
+}
+
+// Update - change state under lock and save it to file
+func (store *Store) Update(fn func(data *Data) error) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if err := fn(&store.data); err != nil {
+		return err
+	}
+	return store.save()
+}
+
+// save - atomically write state to file
+func (store *Store) save() error {
+	content, err := json.Marshal(&store.data)
+	if err != nil {
+		return err
+	}
+	tmpFile, err := ioutil.TempFile(filepath.Dir(store.path), filepath.Base(store.path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmpFile.Write(content); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpFile.Name())
+		return err
+	}
+	return os.Rename(tmpFile.Name(), store.path)
+}
+
+// init - create maps missing in loaded state
+func (data *Data) init() {
+	if data.Users == nil {
+		data.Users = map[int]*User{}
+	}
+	if data.Messages == nil {
+		data.Messages = map[string]MessageRef{}
+	}
+	if data.Threads == nil {
+		data.Threads = map[string]Thread{}
+	}
+	if data.Cards == nil {
+		data.Cards = map[string]Card{}
+	}
+	if data.TopicModes == nil {
+		data.TopicModes = map[int64]string{}
+	}
+	if data.Topics == nil {
+		data.Topics = map[string]int{}
+	}
+	if data.Alerts == nil {
+		data.Alerts = map[string]Alert{}
+	}
+	if data.Unmapped == nil {
+		data.Unmapped = map[string]time.Time{}
+	}
+	if data.PollCursors == nil {
+		data.PollCursors = map[int]PollCursor{}
+	}
+}
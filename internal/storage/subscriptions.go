@@ -0,0 +1,44 @@
+package storage
+
+import "time"
+
+// Subscription statuses. Users without status haven't started the bot yet but still receive notifications
+const (
+	StatusActive  = "active"
+	StatusStopped = "stopped"
+	StatusBlocked = "blocked"
+)
+
+// Receives - check if notifications may be sent to user
+func (user User) Receives() bool {
+	return user.Status != StatusStopped && user.Status != StatusBlocked
+}
+
+// SetStatus - change user's subscription status. Events postponed for unsubscribed user are dropped
+func (store *Store) SetStatus(telegramID int, status string, now time.Time) error {
+	return store.Update(func(data *Data) error {
+		user := data.user(telegramID)
+		if user.Status != status {
+			user.Status = status
+			user.StatusChangedAt = now
+		}
+		if !user.Receives() {
+			user.Held = nil
+			user.DigestEvents = nil
+		}
+		return nil
+	})
+}
+
+// Subscribers - IDs of users who started the bot and didn't stop or block it
+func (store *Store) Subscribers() []int {
+	var telegramIDs []int
+	store.View(func(data *Data) {
+		for telegramID, user := range data.Users {
+			if user.Status == StatusActive {
+				telegramIDs = append(telegramIDs, telegramID)
+			}
+		}
+	})
+	return telegramIDs
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// Thread - first message sent to chat about the issue. Later messages are replies to it
+type Thread struct {
+	MessageID int       `json:"message_id"`
+	ClosedAt  time.Time `json:"closed_at,omitempty"`
+}
+
+func threadKey(chatID int64, issueKey string) string {
+	return fmt.Sprintf("%d:%s", chatID, issueKey)
+}
+
+// expired - thread of issue closed longer than expiry ago is not continued
+func (thread Thread) expired(now time.Time, expiry time.Duration) bool {
+	return !thread.ClosedAt.IsZero() && now.Sub(thread.ClosedAt) > expiry
+}
+
+// GetThread - get first message about the issue in chat
+func (store *Store) GetThread(chatID int64, issueKey string, now time.Time, expiry time.Duration) (int, bool) {
+	var messageID int
+	var found bool
+	store.View(func(data *Data) {
+		thread, exists := data.Threads[threadKey(chatID, issueKey)]
+		if exists && !thread.expired(now, expiry) {
+			messageID, found = thread.MessageID, true
+		}
+	})
+	return messageID, found
+}
+
+// TrackThread - start thread with message if there is no active one and remember issue state
+func (store *Store) TrackThread(chatID int64, issueKey string, messageID int, closed bool, now time.Time, expiry time.Duration) error {
+	return store.Update(func(data *Data) error {
+		for key, thread := range data.Threads {
+			if thread.expired(now, expiry) {
+				delete(data.Threads, key)
+			}
+		}
+		key := threadKey(chatID, issueKey)
+		thread, exists := data.Threads[key]
+		if !exists {
+			thread = Thread{MessageID: messageID}
+		}
+		if !closed {
+			thread.ClosedAt = time.Time{}
+		} else if thread.ClosedAt.IsZero() {
+			thread.ClosedAt = now
+		}
+		data.Threads[key] = thread
+		return nil
+	})
+}
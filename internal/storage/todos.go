@@ -0,0 +1,24 @@
+package storage
+
+// SetTodoPush - enable or disable pushing new To-Dos and remember the last seen one
+func (store *Store) SetTodoPush(telegramID int, enabled bool, lastTodoID int) error {
+	return store.Update(func(data *Data) error {
+		user := data.user(telegramID)
+		user.TodoPush = enabled
+		user.LastTodoID = lastTodoID
+		return nil
+	})
+}
+
+// UsersWithTodoPush - IDs of users who want new To-Dos pushed
+func (store *Store) UsersWithTodoPush() []int {
+	var telegramIDs []int
+	store.View(func(data *Data) {
+		for telegramID, user := range data.Users {
+			if user.TodoPush {
+				telegramIDs = append(telegramIDs, telegramID)
+			}
+		}
+	})
+	return telegramIDs
+}
@@ -0,0 +1,57 @@
+package storage
+
+import "fmt"
+
+// Topic modes of forum chats
+const (
+	TopicsPerProject = "project"
+	TopicsPerIssue   = "issue"
+)
+
+// topicKey - key of forum topic of project or issue in chat
+func topicKey(chatID int64, subject string) string {
+	return fmt.Sprintf("%d:%s", chatID, subject)
+}
+
+// TopicMode - how events are spread over forum topics of chat, empty if topics are not used
+func (store *Store) TopicMode(chatID int64) string {
+	var mode string
+	store.View(func(data *Data) {
+		mode = data.TopicModes[chatID]
+	})
+	return mode
+}
+
+// SetTopicMode - set topic mode of chat, empty mode disables topics
+func (store *Store) SetTopicMode(chatID int64, mode string) error {
+	return store.Update(func(data *Data) error {
+		if mode == "" {
+			delete(data.TopicModes, chatID)
+		} else {
+			data.TopicModes[chatID] = mode
+		}
+		return nil
+	})
+}
+
+// GetTopic - forum topic created for project or issue in chat
+func (store *Store) GetTopic(chatID int64, subject string) (int, bool) {
+	var topicID int
+	var found bool
+	store.View(func(data *Data) {
+		topicID, found = data.Topics[topicKey(chatID, subject)]
+	})
+	return topicID, found
+}
+
+// SaveTopic - remember forum topic of project or issue, zero topic ID forgets it
+func (store *Store) SaveTopic(chatID int64, subject string, topicID int) error {
+	return store.Update(func(data *Data) error {
+		if topicID == 0 {
+			delete(data.Topics, topicKey(chatID, subject))
+		} else {
+			data.Topics[topicKey(chatID, subject)] = topicID
+		}
+		return nil
+	})
+}
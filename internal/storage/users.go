@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	config "github.com/aberestyak/gitlab-issue-bot/internal/config"
+	issue "github.com/aberestyak/gitlab-issue-bot/pkg/model"
+)
+
+// User - telegram user linked with gitlab account
+type User struct {
+	TelegramID      int                  `json:"telegram_id"`
+	GitlabID        int                  `json:"gitlab_id"`
+	GitlabUsername  string               `json:"gitlab_username"`
+	Status          string               `json:"status,omitempty"`
+	StatusChangedAt time.Time            `json:"status_changed_at,omitempty"`
+	Muted           map[string]Mute      `json:"muted,omitempty"`
+	Style           string               `json:"style,omitempty"`
+	Settings        Settings             `json:"settings"`
+	Filters         []Filter             `json:"filters,omitempty"`
+	Quiet           QuietHours           `json:"quiet"`
+	Held            []issue.SummaryEvent `json:"held,omitempty"`
+	Digest          Digest               `json:"digest"`
+	DigestEvents    []issue.SummaryEvent `json:"digest_events,omitempty"`
+	TodoPush        bool                 `json:"todo_push,omitempty"`
+	LastTodoID      int                  `json:"last_todo_id,omitempty"`
+	RecentProjects  []string             `json:"recent_projects,omitempty"`
+	Accounts        map[string]Account   `json:"accounts,omitempty"`
+}
+
+// Account - gitlab account of user on additional instance
+type Account struct {
+	GitlabID       int    `json:"gitlab_id"`
+	GitlabUsername string `json:"gitlab_username"`
+}
+
+// On - copy of user with gitlab account of instance. Empty instance is the default one
+func (user User) On(instance string) User {
+	if instance == "" || instance == config.DefaultInstance {
+		return user
+	}
+	account := user.Accounts[instance]
+	user.GitlabID = account.GitlabID
+	user.GitlabUsername = account.GitlabUsername
+	return user
+}
+
+// Settings - user's preferences of events to receive
+type Settings struct {
+	DisabledEvents         []string `json:"disabled_events,omitempty"`
+	CommentsOnlyAssigned   bool     `json:"comments_only_assigned,omitempty"`
+	IgnoreLabelOnlyUpdates bool     `json:"ignore_label_only_updates,omitempty"`
+	MentionsOverrideMute   bool     `json:"mentions_override_mute,omitempty"`
+}
+
+// EventEnabled - check if user wants to receive events of kind
+func (settings Settings) EventEnabled(kind string) bool {
+	for _, disabled := range settings.DisabledEvents {
+		if disabled == kind {
+			return false
+		}
+	}
+	return true
+}
+
+// ToggleEvent - enable disabled event kind and vice versa
+func (settings *Settings) ToggleEvent(kind string) {
+	if settings.EventEnabled(kind) {
+		settings.DisabledEvents = append(settings.DisabledEvents, kind)
+		return
+	}
+	var disabledEvents []string
+	for _, disabled := range settings.DisabledEvents {
+		if disabled != kind {
+			disabledEvents = append(disabledEvents, disabled)
+		}
+	}
+	settings.DisabledEvents = disabledEvents
+}
+
+// IssueKey - key to identify issue across projects
+func IssueKey(projectID int, issueIID int) string {
+	return fmt.Sprintf("%d#%d", projectID, issueIID)
+}
+
+// InstanceIssueKey - key to identify issue across instances. Keys of default instance have no prefix
+func InstanceIssueKey(instance string, projectID int, issueIID int) string {
+	if instance == "" || instance == config.DefaultInstance {
+		return IssueKey(projectID, issueIID)
+	}
+	return instance + ":" + IssueKey(projectID, issueIID)
+}
+
+// user - get user or create new one
+func (data *Data) user(telegramID int) *User {
+	user, found := data.Users[telegramID]
+	if !found {
+		user = &User{TelegramID: telegramID}
+		data.Users[telegramID] = user
+	}
+	return user
+}
+
+// clone - copy of user sharing no maps and slices with it, so copy can be read after store is unlocked
+func (user User) clone() User {
+	if user.Muted != nil {
+		muted := make(map[string]Mute, len(user.Muted))
+		for key, mute := range user.Muted {
+			muted[key] = mute
+		}
+		user.Muted = muted
+	}
+	if user.Accounts != nil {
+		accounts := make(map[string]Account, len(user.Accounts))
+		for instance, account := range user.Accounts {
+			accounts[instance] = account
+		}
+		user.Accounts = accounts
+	}
+	user.Settings.DisabledEvents = append([]string(nil), user.Settings.DisabledEvents...)
+	user.Filters = append([]Filter(nil), user.Filters...)
+	user.Held = append([]issue.SummaryEvent(nil), user.Held...)
+	user.DigestEvents = append([]issue.SummaryEvent(nil), user.DigestEvents...)
+	user.RecentProjects = append([]string(nil), user.RecentProjects...)
+	return user
+}
+
+// GetUser - get copy of stored user
+func (store *Store) GetUser(telegramID int) (User, bool) {
+	var user User
+	var found bool
+	store.View(func(data *Data) {
+		var stored *User
+		if stored, found = data.Users[telegramID]; found {
+			user = stored.clone()
+		}
+	})
+	return user, found
+}
+
+// LinkUser - remember gitlab account of telegram user on instance
+func (store *Store) LinkUser(telegramID int, instance string, gitlabID int, gitlabUsername string) error {
+	return store.Update(func(data *Data) error {
+		user := data.user(telegramID)
+		if instance == "" || instance == config.DefaultInstance {
+			user.GitlabID = gitlabID
+			user.GitlabUsername = gitlabUsername
+			return nil
+		}
+		if user.Accounts == nil {
+			user.Accounts = map[string]Account{}
+		}
+		user.Accounts[instance] = Account{GitlabID: gitlabID, GitlabUsername: gitlabUsername}
+		return nil
+	})
+}
+
+// UnlinkGitlabUser - forget gitlab account of instance. Returns telegram IDs of unlinked users
+func (store *Store) UnlinkGitlabUser(instance string, gitlabID int) ([]int, error) {
+	var telegramIDs []int
+	err := store.Update(func(data *Data) error {
+		for telegramID, user := range data.Users {
+			if instance == "" || instance == config.DefaultInstance {
+				if user.GitlabID == gitlabID {
+					user.GitlabID = 0
+					user.GitlabUsername = ""
+					telegramIDs = append(telegramIDs, telegramID)
+				}
+			} else if account, found := user.Accounts[instance]; found && account.GitlabID == gitlabID {
+				delete(user.Accounts, instance)
+				telegramIDs = append(telegramIDs, telegramID)
+			}
+		}
+		return nil
+	})
+	return telegramIDs, err
+}
+
+// SetStyle - set user's notifications style
+func (store *Store) SetStyle(telegramID int, style string) error {
+	return store.Update(func(data *Data) error {
+		data.user(telegramID).Style = style
+		return nil
+	})
+}
+
+// UpdateSettings - change user's settings. Returns updated settings
+func (store *Store) UpdateSettings(telegramID int, fn func(settings *Settings)) (Settings, error) {
+	var settings Settings
+	err := store.Update(func(data *Data) error {
+		user := data.user(telegramID)
+		fn(&user.Settings)
+		settings = user.Settings
+		return nil
+	})
+	return settings, err
+}
@@ -0,0 +1,44 @@
+package telegram
+
+import (
+	gitlabUserAPI "github.com/aberestyak/gitlab-issue-bot/internal/gitlabAPI"
+	issue "github.com/aberestyak/gitlab-issue-bot/pkg/model"
+	"github.com/xanzy/go-gitlab"
+)
+
+// permitted - check recipient may see the event in GitLab. Access check errors deny delivery
+func (bot *Bot) permitted(botUser issue.BotUser, n *notification) bool {
+	if botUser.GitlabID == 0 {
+		return false
+	}
+	accessLevel, err := gitlabUserAPI.GetAccessLevel(n.Attributes.ProjectID, botUser.GitlabID, bot.client(n.Instance))
+	if err != nil {
+		telegramLogger.Errorf("Issue #%d. Can't check access of %s to project %d: %s", n.Attributes.ID, botUser.Name, n.Attributes.ProjectID, err.Error())
+		return false
+	}
+	return canRead(accessLevel, !n.PrivateProject, botUser.GitlabID, n.Attributes, n.InternalNote)
+}
+
+// canRead - check user with access level may read issue or its internal note. Issues of public
+// and internal projects are readable by everyone, of private ones by members. Confidential
+// issues are readable by reporters, author and assignees, internal notes by reporters only
+func canRead(accessLevel gitlab.AccessLevelValue, openProject bool, gitlabID int, attributes issue.Attibutes, internalNote bool) bool {
+	if internalNote {
+		return accessLevel >= gitlab.ReporterPermissions
+	}
+	if accessLevel < gitlab.GuestPermissions && !openProject {
+		return false
+	}
+	if !attributes.Confidential || accessLevel >= gitlab.ReporterPermissions {
+		return true
+	}
+	if attributes.IssueBodyAuthor == gitlabID {
+		return true
+	}
+	for _, assigneeID := range attributes.Assignee {
+		if assigneeID == gitlabID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package telegram
+
+import (
+	"testing"
+
+	issue "github.com/aberestyak/gitlab-issue-bot/pkg/model"
+	"github.com/xanzy/go-gitlab"
+)
+
+func TestCanRead(t *testing.T) {
+	const (
+		author   = 1
+		assignee = 2
+		other    = 3
+	)
+	public := issue.Attibutes{IssueBodyAuthor: author, Assignee: []int{assignee}}
+	confidential := issue.Attibutes{IssueBodyAuthor: author, Assignee: []int{assignee}, Confidential: true}
+	tests := []struct {
+		name         string
+		accessLevel  gitlab.AccessLevelValue
+		openProject  bool
+		gitlabID     int
+		attributes   issue.Attibutes
+		internalNote bool
+		want         bool
+	}{
+		{"guest reads issue", gitlab.GuestPermissions, false, other, public, false, true},
+		{"non-member of private project", gitlab.NoPermissions, false, other, public, false, false},
+		{"non-member of public project", gitlab.NoPermissions, true, other, public, false, true},
+		{"guest and confidential issue", gitlab.GuestPermissions, false, other, confidential, false, false},
+		{"guest of public project and confidential issue", gitlab.GuestPermissions, true, other, confidential, false, false},
+		{"non-member and confidential issue of public project", gitlab.NoPermissions, true, other, confidential, false, false},
+		{"author of confidential issue", gitlab.GuestPermissions, false, author, confidential, false, true},
+		{"assignee of confidential issue", gitlab.GuestPermissions, false, assignee, confidential, false, true},
+		{"reporter and confidential issue", gitlab.ReporterPermissions, false, other, confidential, false, true},
+		{"author removed from private project", gitlab.NoPermissions, false, author, confidential, false, false},
+		{"guest and internal note", gitlab.GuestPermissions, true, other, public, true, false},
+		{"author and internal note", gitlab.GuestPermissions, false, author, confidential, true, false},
+		{"reporter and internal note", gitlab.ReporterPermissions, false, other, public, true, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := canRead(test.accessLevel, test.openProject, test.gitlabID, test.attributes, test.internalNote); got != test.want {
+				t.Errorf("canRead() = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package telegram
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	config "github.com/aberestyak/gitlab-issue-bot/internal/config"
+	issue "github.com/aberestyak/gitlab-issue-bot/pkg/model"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+// unmappedListLimit - max recipients shown by /unmapped
+const unmappedListLimit = 50
+
+// deliveryStats - counters of processed events since bot start
+type deliveryStats struct {
+	sync.Mutex
+	Since      time.Time
+	Events     int
+	Deliveries int
+	Failures   int
+}
+
+// countEvent - count received gitlab event
+func (stats *deliveryStats) countEvent() {
+	stats.Lock()
+	defer stats.Unlock()
+	stats.Events++
+}
+
+// countDelivery - count sent or failed notification
+func (stats *deliveryStats) countDelivery(err error) {
+	stats.Lock()
+	defer stats.Unlock()
+	if err != nil {
+		stats.Failures++
+	} else {
+		stats.Deliveries++
+	}
+}
+
+// String - stats summary
+func (stats *deliveryStats) String() string {
+	stats.Lock()
+	defer stats.Unlock()
+	return fmt.Sprintf("Since %s:\nEvents processed: %d\nNotifications delivered: %d\nDelivery failures: %d",
+		stats.Since.Format("2006-01-02 15:04 MST"), stats.Events, stats.Deliveries, stats.Failures)
+}
+
+// isAdminID - check telegram user is bot administrator
+func (bot *Bot) isAdminID(telegramID int) bool {
+	for _, adminID := range bot.conf().AdminIDs {
+		if adminID == telegramID {
+			return true
+		}
+	}
+	return false
+}
+
+// isAdmin - check message sender is bot administrator. Admin commands work in private chats only
+func (bot *Bot) isAdmin(m *tb.Message) bool {
+	if m.Private() && bot.isAdminID(m.Sender.ID) {
+		return true
+	}
+	telegramLogger.Warnf("Admin command %s from non admin %d is ignored", m.Text, m.Sender.ID)
+	return false
+}
+
+// handleStats - show delivery counters
+func (bot *Bot) handleStats(m *tb.Message) {
+	if !bot.isAdmin(m) {
+		return
+	}
+	bot.reply(m, fmt.Sprintf("%s\nSubscribers: %d", bot.stats, len(bot.store.Subscribers())))
+}
+
+// handleUsers - list users linked with gitlab accounts
+func (bot *Bot) handleUsers(m *tb.Message) {
+	if !bot.isAdmin(m) {
+		return
+	}
+	users := bot.store.LinkedUsers()
+	if len(users) == 0 {
+		bot.reply(m, "No linked users")
+		return
+	}
+	lines := []string{fmt.Sprintf("Linked users (%d):", len(users))}
+	for _, user := range users {
+		line := fmt.Sprintf("%s — %d", user.GitlabUsername, user.TelegramID)
+		var instances []string
+		for instance, account := range user.Accounts {
+			instances = append(instances, fmt.Sprintf("%s@%s", account.GitlabUsername, instance))
+		}
+		sort.Strings(instances)
+		if len(instances) > 0 {
+			line += fmt.Sprintf(" (%s)", strings.Join(instances, ", "))
+		}
+		if user.Status != "" {
+			line += ", " + user.Status
+		}
+		lines = append(lines, line)
+	}
+	for _, text := range issue.SplitLines(lines, issue.MessageLimit) {
+		bot.reply(m, text)
+	}
+}
+
+// handleBroadcast - send announcement to all subscribers
+func (bot *Bot) handleBroadcast(m *tb.Message) {
+	if !bot.isAdmin(m) {
+		return
+	}
+	text := strings.TrimSpace(m.Payload)
+	if text == "" {
+		bot.reply(m, "Usage: /broadcast <text>")
+		return
+	}
+	sent, failed := 0, 0
+	for _, telegramID := range bot.store.Subscribers() {
+		_, err := bot.telegram.Send(&tb.Chat{ID: int64(telegramID)}, "📣 "+text)
+		bot.checkChatGone(int64(telegramID), err)
+		if err != nil {
+			telegramLogger.Errorf("Can't broadcast to user %d: %s", telegramID, err.Error())
+			failed++
+			continue
+		}
+		sent++
+	}
+	telegramLogger.Infof("Broadcast by admin %d sent to %d users, failed for %d", m.Sender.ID, sent, failed)
+	bot.reply(m, fmt.Sprintf("Broadcast sent to %d users, failed for %d", sent, failed))
+}
+
+// handleUnmapped - list gitlab recipients without Telegram
+func (bot *Bot) handleUnmapped(m *tb.Message) {
+	if !bot.isAdmin(m) {
+		return
+	}
+	users := bot.store.UnmappedUsers()
+	if len(users) == 0 {
+		bot.reply(m, "All recipients have Telegram")
+		return
+	}
+	lines := []string{fmt.Sprintf("Recipients without Telegram (%d):", len(users))}
+	for i, user := range users {
+		if i == unmappedListLimit {
+			lines = append(lines, "...")
+			break
+		}
+		lines = append(lines, fmt.Sprintf("%s, last event %s", user.Name, user.LastSeen.Format("2006-01-02")))
+	}
+	bot.reply(m, strings.Join(lines, "\n"))
+}
+
+// handleReload - re-read configuration. Tokens, instances, storage and listen address need restart
+func (bot *Bot) handleReload(m *tb.Message) {
+	if !bot.isAdmin(m) {
+		return
+	}
+	reloaded, err := config.LoadConfig()
+	if err != nil {
+		telegramLogger.Errorf("Configuration reload by admin %d failed: %s", m.Sender.ID, err.Error())
+		bot.reply(m, fmt.Sprintf("Configuration is not reloaded, previous one is kept: %s", err.Error()))
+		return
+	}
+	bot.configLock.Lock()
+	current := bot.config
+	reloaded.ListenPort = current.ListenPort
+	reloaded.ListenLocation = current.ListenLocation
+	reloaded.TelegramToken = current.TelegramToken
+	reloaded.GitlabToken = current.GitlabToken
+	reloaded.GitlabURL = current.GitlabURL
+	reloaded.CallbackSecret = current.CallbackSecret
+	reloaded.StoragePath = current.StoragePath
+	reloaded.Instances = current.Instances
+	bot.config = reloaded
+	bot.configLock.Unlock()
+	telegramLogger.Infof("Configuration reloaded by admin %d", m.Sender.ID)
+	bot.reply(m, "Configuration reloaded. Changes of tokens, GitLab instances, storage path and listen address need restart")
+}
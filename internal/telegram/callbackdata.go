@@ -0,0 +1,102 @@
+package telegram
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Telegram rejects buttons with callback data longer than 64 bytes
+	maxCallbackDataLength = 64
+	callbackSignLength    = 10
+	labelHashLength       = 6
+	callbackSeparator     = ":"
+)
+
+// Callback actions. Kept short to fit into callback data limit
+const (
+	ActionClose    = "cl"
+	ActionReopen   = "ro"
+	ActionAssignMe = "as"
+	ActionAddLabel = "la"
+	ActionMute     = "mu"
+	ActionUnmute   = "um"
+	ActionDueMenu  = "dm"
+	ActionDueDate  = "dd"
+	ActionBack     = "bk"
+	ActionSetting  = "st"
+	ActionMyPage   = "mp"
+	ActionTodoDone = "td"
+	ActionAck      = "ak"
+)
+
+// CallbackData - payload of inline keyboard button
+type CallbackData struct {
+	Action    string
+	ProjectID int
+	IssueIID  int
+	Arg       string
+}
+
+// Encode - serialize and sign callback data
+func (data CallbackData) Encode(secret []byte) (string, error) {
+	if strings.Contains(data.Action, callbackSeparator) || strings.Contains(data.Arg, callbackSeparator) {
+		return "", fmt.Errorf("callback data for action %s contains separator", data.Action)
+	}
+	payload := strings.Join([]string{
+		data.Action,
+		strconv.FormatInt(int64(data.ProjectID), 36),
+		strconv.FormatInt(int64(data.IssueIID), 36),
+		data.Arg,
+	}, callbackSeparator)
+	encoded := payload + callbackSeparator + signCallback(secret, payload)
+	if len(encoded) > maxCallbackDataLength {
+		return "", fmt.Errorf("callback data for action %s is %d bytes long", data.Action, len(encoded))
+	}
+	return encoded, nil
+}
+
+// DecodeCallbackData - verify signature and deserialize callback data
+func DecodeCallbackData(secret []byte, encoded string) (CallbackData, error) {
+	if len(encoded) > maxCallbackDataLength {
+		return CallbackData{}, fmt.Errorf("callback data is %d bytes long", len(encoded))
+	}
+	separatorIndex := strings.LastIndex(encoded, callbackSeparator)
+	if separatorIndex < 0 {
+		return CallbackData{}, errors.New("callback data is not signed")
+	}
+	payload, sign := encoded[:separatorIndex], encoded[separatorIndex+1:]
+	if !hmac.Equal([]byte(sign), []byte(signCallback(secret, payload))) {
+		return CallbackData{}, errors.New("callback data has wrong signature")
+	}
+	fields := strings.Split(payload, callbackSeparator)
+	if len(fields) != 4 {
+		return CallbackData{}, fmt.Errorf("callback data has %d fields", len(fields))
+	}
+	projectID, err := strconv.ParseInt(fields[1], 36, 0)
+	if err != nil {
+		return CallbackData{}, err
+	}
+	issueIID, err := strconv.ParseInt(fields[2], 36, 0)
+	if err != nil {
+		return CallbackData{}, err
+	}
+	return CallbackData{Action: fields[0], ProjectID: int(projectID), IssueIID: int(issueIID), Arg: fields[3]}, nil
+}
+
+// labelHash - short label digest for callback data. Labels may be long and contain separator
+func labelHash(label string) string {
+	sum := sha256.Sum256([]byte(label))
+	return base64.RawURLEncoding.EncodeToString(sum[:])[:labelHashLength]
+}
+
+func signCallback(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))[:callbackSignLength]
+}
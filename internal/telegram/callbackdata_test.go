@@ -0,0 +1,87 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCallbackDataRoundTrip(t *testing.T) {
+	secret := []byte("secret")
+	tests := []CallbackData{
+		{Action: ActionClose, ProjectID: 42, IssueIID: 7},
+		{Action: ActionAddLabel, ProjectID: 123456789, IssueIID: 99999, Arg: labelHash("priority::high")},
+		{Action: ActionDueDate, ProjectID: 1, IssueIID: 1, Arg: "14"},
+	}
+	for _, data := range tests {
+		encoded, err := data.Encode(secret)
+		if err != nil {
+			t.Fatalf("Encode(%+v): %s", data, err)
+		}
+		decoded, err := DecodeCallbackData(secret, encoded)
+		if err != nil {
+			t.Fatalf("DecodeCallbackData(%q): %s", encoded, err)
+		}
+		if decoded != data {
+			t.Errorf("DecodeCallbackData(%q) = %+v, want %+v", encoded, decoded, data)
+		}
+	}
+}
+
+func TestDecodeCallbackDataRejected(t *testing.T) {
+	secret := []byte("secret")
+	encoded, err := CallbackData{Action: ActionClose, ProjectID: 42, IssueIID: 7}.Encode(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	separatorIndex := strings.LastIndex(encoded, callbackSeparator)
+	payload, sign := encoded[:separatorIndex], encoded[separatorIndex+1:]
+	otherSign, err := CallbackData{Action: ActionClose, ProjectID: 42, IssueIID: 8}.Encode(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSign = otherSign[strings.LastIndex(otherSign, callbackSeparator)+1:]
+	tamperedSign := "A" + sign[1:]
+	if sign[0] == 'A' {
+		tamperedSign = "B" + sign[1:]
+	}
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"unsigned", payload},
+		{"empty signature", payload + callbackSeparator},
+		{"tampered payload", strings.Replace(payload, ActionClose, ActionReopen, 1) + callbackSeparator + sign},
+		{"signature of other payload", payload + callbackSeparator + otherSign},
+		{"tampered signature", payload + callbackSeparator + tamperedSign},
+		{"truncated signature", payload + callbackSeparator + sign[:callbackSignLength-1]},
+		{"oversized signature", payload + callbackSeparator + sign + "A"},
+		{"oversized data", payload + callbackSeparator + sign + strings.Repeat("A", maxCallbackDataLength)},
+		{"other secret", encoded[:separatorIndex+1] + signCallback([]byte("other"), payload)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if data, err := DecodeCallbackData(secret, test.encoded); err == nil {
+				t.Errorf("DecodeCallbackData(%q) = %+v, want error", test.encoded, data)
+			}
+		})
+	}
+}
+
+func TestCallbackDataEncodeRejected(t *testing.T) {
+	tests := []struct {
+		name string
+		data CallbackData
+	}{
+		{"separator in arg", CallbackData{Action: ActionAddLabel, Arg: "scope::label"}},
+		{"separator in action", CallbackData{Action: "a:b"}},
+		{"too long", CallbackData{Action: ActionAddLabel, Arg: strings.Repeat("l", maxCallbackDataLength)}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if encoded, err := test.data.Encode([]byte("secret")); err == nil {
+				t.Errorf("Encode(%+v) = %q, want error", test.data, encoded)
+			}
+		})
+	}
+}
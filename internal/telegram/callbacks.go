@@ -0,0 +1,224 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	gitlabUserAPI "github.com/aberestyak/gitlab-issue-bot/internal/gitlabAPI"
+	storage "github.com/aberestyak/gitlab-issue-bot/internal/storage"
+	issue "github.com/aberestyak/gitlab-issue-bot/pkg/model"
+	"github.com/xanzy/go-gitlab"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+var (
+	errNotPermitted = errors.New("not permitted")
+	errUnknownLabel = errors.New("label is not in shortlist anymore")
+)
+
+// handleCallback - handle inline keyboard button press
+func (bot *Bot) handleCallback(c *tb.Callback) {
+	data, err := DecodeCallbackData(bot.conf().CallbackSecret, c.Data)
+	if err != nil {
+		telegramLogger.Warnf("Callback from user %d rejected: %s", c.Sender.ID, err.Error())
+		bot.respond(c, "Unknown button", true)
+		return
+	}
+
+	switch data.Action {
+	case ActionMute:
+		bot.muteFromCallback(c, data)
+		return
+	case ActionUnmute:
+		bot.unmuteFromCallback(c, data)
+		return
+	case ActionSetting:
+		bot.toggleSetting(c, data)
+		return
+	case ActionMyPage:
+		bot.myPageFromCallback(c, data)
+		return
+	case ActionTodoDone:
+		bot.todoDoneFromCallback(c, data)
+		return
+	case ActionAck:
+		bot.ackFromCallback(c, data)
+		return
+	}
+
+	instance, known := bot.messageInstance(c.Message)
+	if !known {
+		bot.respond(c, "This message is too old, open the issue in GitLab", true)
+		return
+	}
+	gitlabClient := bot.client(instance)
+	user, linked := bot.store.GetUser(c.Sender.ID)
+	user = user.On(instance)
+	if !linked || user.GitlabID == 0 {
+		bot.respond(c, "Link your GitLab account first: /link <gitlab username>", true)
+		return
+	}
+
+	gitlabIssue, _, err := gitlabClient.Issues.GetIssue(data.ProjectID, data.IssueIID)
+	if err != nil {
+		telegramLogger.Errorf("Can't get issue #%d of project %d: %s", data.IssueIID, data.ProjectID, err.Error())
+		bot.respond(c, "Can't get issue from GitLab", true)
+		return
+	}
+	attributes := issue.AttributesFromGitlab(gitlabIssue)
+
+	switch data.Action {
+	case ActionDueMenu:
+		bot.editMarkup(c, bot.withAck(c, instance, DueDateKeyboard(bot.conf().CallbackSecret, attributes), attributes))
+		bot.respond(c, "", false)
+		return
+	case ActionBack:
+		bot.editMarkup(c, bot.withAck(c, instance, bot.issueKeyboard(attributes), attributes))
+		bot.respond(c, "", false)
+		return
+	}
+
+	if err := bot.checkPermission(gitlabClient, user, data.Action, gitlabIssue); err != nil {
+		if errors.Is(err, errNotPermitted) {
+			telegramLogger.Warnf("Gitlab user %s isn't permitted to %s issue #%d of project %d", user.GitlabUsername, data.Action, data.IssueIID, data.ProjectID)
+			bot.respond(c, "You are not permitted to do this", true)
+		} else {
+			bot.respond(c, "Can't check your permissions in GitLab", true)
+		}
+		return
+	}
+
+	updatedIssue, result, err := bot.applyAction(gitlabClient, user, data, gitlabIssue)
+	if errors.Is(err, errUnknownLabel) {
+		bot.editMarkup(c, bot.withAck(c, instance, bot.issueKeyboard(attributes), attributes))
+		bot.respond(c, "This label is not offered anymore", true)
+		return
+	}
+	if err != nil {
+		telegramLogger.Errorf("Can't %s issue #%d of project %d: %s", data.Action, data.IssueIID, data.ProjectID, err.Error())
+		bot.respond(c, "GitLab request failed", true)
+		return
+	}
+	telegramLogger.Infof("Issue #%d of project %d: %s by %s", data.IssueIID, data.ProjectID, result, user.GitlabUsername)
+
+	// Text keeps its style, comment or card, and is refreshed by the webhook of this change
+	attributes = issue.AttributesFromGitlab(updatedIssue)
+	bot.editMarkup(c, bot.withAck(c, instance, bot.issueKeyboard(attributes), attributes))
+	bot.respond(c, result, false)
+}
+
+// checkPermission - check that linked gitlab user may perform action on the issue
+func (bot *Bot) checkPermission(gitlabClient *gitlab.Client, user storage.User, action string, gitlabIssue *gitlab.Issue) error {
+	// Authors may close and reopen their own issues
+	if (action == ActionClose || action == ActionReopen) && gitlabIssue.Author != nil && gitlabIssue.Author.ID == user.GitlabID {
+		return nil
+	}
+	accessLevel, err := gitlabUserAPI.GetAccessLevel(gitlabIssue.ProjectID, user.GitlabID, gitlabClient)
+	if err != nil {
+		return err
+	}
+	if accessLevel < gitlab.ReporterPermissions {
+		return errNotPermitted
+	}
+	return nil
+}
+
+// applyAction - perform callback action against gitlab API. Returns updated issue and result description
+func (bot *Bot) applyAction(gitlabClient *gitlab.Client, user storage.User, data CallbackData, gitlabIssue *gitlab.Issue) (*gitlab.Issue, string, error) {
+	options := &gitlab.UpdateIssueOptions{}
+	var result string
+	switch data.Action {
+	case ActionClose:
+		options.StateEvent = gitlab.String("close")
+		result = fmt.Sprintf("Issue #%d closed", data.IssueIID)
+	case ActionReopen:
+		options.StateEvent = gitlab.String("reopen")
+		result = fmt.Sprintf("Issue #%d reopened", data.IssueIID)
+	case ActionAssignMe:
+		assigneeIDs := []int{user.GitlabID}
+		for _, assignee := range gitlabIssue.Assignees {
+			if assignee.ID != user.GitlabID {
+				assigneeIDs = append(assigneeIDs, assignee.ID)
+			}
+		}
+		options.AssigneeIDs = assigneeIDs
+		result = fmt.Sprintf("Issue #%d assigned to you", data.IssueIID)
+	case ActionAddLabel:
+		// Shortlist may be reloaded after the button was sent
+		label := ""
+		for _, shortlisted := range bot.conf().LabelShortlist {
+			if labelHash(shortlisted) == data.Arg {
+				label = shortlisted
+			}
+		}
+		if label == "" {
+			return nil, "", errUnknownLabel
+		}
+		options.AddLabels = gitlab.Labels{label}
+		result = fmt.Sprintf("Label %s added", label)
+	case ActionDueDate:
+		days, err := strconv.Atoi(data.Arg)
+		if err != nil {
+			return nil, "", fmt.Errorf("wrong due date %s", data.Arg)
+		}
+		dueDate := gitlab.ISOTime(time.Now().AddDate(0, 0, days))
+		options.DueDate = &dueDate
+		result = fmt.Sprintf("Due date set to %s", dueDate.String())
+	default:
+		return nil, "", fmt.Errorf("unknown action %s", data.Action)
+	}
+	updatedIssue, _, err := gitlabClient.Issues.UpdateIssue(data.ProjectID, data.IssueIID, options, bot.actAs(user)...)
+	if err != nil {
+		return nil, "", err
+	}
+	return updatedIssue, result, nil
+}
+
+// messageInstance - gitlab instance of issue the message is about. Callback data doesn't
+// carry instance, so without remembered message it's known only if there is one instance
+func (bot *Bot) messageInstance(m *tb.Message) (string, bool) {
+	if m != nil && m.Chat != nil {
+		if ref, found := bot.store.GetMessage(m.Chat.ID, m.ID); found {
+			return ref.Instance, true
+		}
+	}
+	return "", len(bot.gitlabs) <= 1
+}
+
+// withAck - keep acknowledge button of unacknowledged alert message as the last row of keyboard
+func (bot *Bot) withAck(c *tb.Callback, instance string, markup *tb.ReplyMarkup, attributes issue.Attibutes) *tb.ReplyMarkup {
+	if c.Message != nil && c.Message.Chat != nil && bot.store.HasAlert(c.Message.Chat.ID, c.Message.ID) {
+		markup.InlineKeyboard = append(markup.InlineKeyboard, []tb.InlineButton{bot.ackButton(instance, attributes)})
+	}
+	return markup
+}
+
+// senderChat - check button is pressed in private chat of its sender. Personal lists are sent
+// to private chats only, so buttons of other chats are not sender's ones
+func senderChat(c *tb.Callback) bool {
+	return c.Message != nil && c.Message.Chat != nil && c.Message.Chat.ID == int64(c.Sender.ID)
+}
+
+// editMarkup - replace inline keyboard of callback message
+func (bot *Bot) editMarkup(c *tb.Callback, markup *tb.ReplyMarkup) {
+	if c.Message == nil {
+		return
+	}
+	if _, err := bot.telegram.EditReplyMarkup(c.Message, markup); err != nil && !isNotModified(err) {
+		telegramLogger.Errorf("Can't update keyboard: %s", err.Error())
+	}
+}
+
+// respond - answer callback query, optionally with alert
+func (bot *Bot) respond(c *tb.Callback, text string, alert bool) {
+	if err := bot.telegram.Respond(c, &tb.CallbackResponse{Text: text, ShowAlert: alert}); err != nil {
+		telegramLogger.Errorf("Can't answer callback: %s", err.Error())
+	}
+}
+
+// isNotModified - telegram refuses edits which don't change the message
+func isNotModified(err error) bool {
+	return err == tb.ErrMessageNotModified || err == tb.ErrSameMessageContent
+}
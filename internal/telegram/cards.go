@@ -0,0 +1,131 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	issue "github.com/aberestyak/gitlab-issue-bot/pkg/model"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+// Notification styles
+const (
+	// StyleMessages - new message for every event
+	StyleMessages = "messages"
+	// StyleCard - single message per issue edited on every event
+	StyleCard = "card"
+	// StyleCardPing - issue card plus short message on important changes
+	StyleCardPing = "card_ping"
+)
+
+var styles = []string{StyleMessages, StyleCard, StyleCardPing}
+
+// importantActions - issue actions which deserve a ping in card style
+var importantActions = map[string]string{
+	"open":   "opened",
+	"close":  "closed",
+	"reopen": "reopened",
+}
+
+// handleStyle - show or change user's notifications style
+func (bot *Bot) handleStyle(m *tb.Message) {
+	style := strings.TrimSpace(m.Payload)
+	if style == "" {
+		bot.reply(m, fmt.Sprintf("Your notifications style is %s. Available styles: %s", bot.styleFor(int64(m.Sender.ID)), strings.Join(styles, ", ")))
+		return
+	}
+	if !isStyle(style) {
+		bot.reply(m, fmt.Sprintf("Unknown style %s. Available styles: %s", style, strings.Join(styles, ", ")))
+		return
+	}
+	if err := bot.store.SetStyle(m.Sender.ID, style); err != nil {
+		telegramLogger.Errorf("Can't save style for user %d: %s", m.Sender.ID, err.Error())
+		bot.reply(m, "Can't save style, try again later")
+		return
+	}
+	bot.reply(m, fmt.Sprintf("Notifications style is set to %s", style))
+}
+
+// styleFor - notifications style of the chat
+func (bot *Bot) styleFor(chatID int64) string {
+	if user, found := bot.store.GetUser(int(chatID)); found && isStyle(user.Style) {
+		return user.Style
+	}
+	return bot.conf().NotificationStyle
+}
+
+func isStyle(style string) bool {
+	for _, known := range styles {
+		if style == known {
+			return true
+		}
+	}
+	return false
+}
+
+// deliverCard - create or update issue card in chat
+func (bot *Bot) deliverCard(chatID int64, n *notification, ping bool) error {
+	now := time.Now()
+	card, found := bot.store.GetCard(chatID, n.issueKey())
+	if n.Comment != "" {
+		card.LastComment = n.Comment
+		card.LastCommentAuthor = n.CommentAuthor
+	}
+	issueCard := &issue.IssueCard{
+		Attributes:        n.Attributes,
+		LastComment:       card.LastComment,
+		LastCommentAuthor: card.LastCommentAuthor,
+	}
+	text := issueCard.BeautifyNotification()
+	options := &tb.SendOptions{ParseMode: tb.ModeMarkdownV2, ReplyMarkup: n.Keyboard, DisableNotification: n.Priority == PriorityLow}
+	// Edited card of unacknowledged alert keeps its acknowledge button
+	if n.Alert || (found && bot.store.HasAlert(chatID, card.MessageID)) {
+		options.ReplyMarkup = bot.alertKeyboard(n)
+	}
+
+	edited := false
+	if found {
+		_, err := bot.telegram.Edit(&tb.Message{ID: card.MessageID, Chat: &tb.Chat{ID: chatID}}, text, options)
+		switch {
+		case err == nil || isNotModified(err):
+			edited = true
+		case isUneditable(err):
+			telegramLogger.Infof("Issue #%d. Card can't be edited anymore, sending new one: %s", n.Attributes.ID, err.Error())
+		default:
+			return err
+		}
+	}
+	if !edited {
+		message, err := bot.telegram.Send(&tb.Chat{ID: chatID}, text, options)
+		if err != nil {
+			return err
+		}
+		card.MessageID = message.ID
+	}
+	// Edited card is remembered again, so replies and buttons work while it's updated
+	bot.rememberMessage(chatID, card.MessageID, &notification{Attributes: n.Attributes, Instance: n.Instance}, now)
+	card.UpdatedAt = now
+	if n.Alert {
+		bot.raiseAlert(chatID, card.MessageID, n, now)
+	}
+	if err := bot.store.SaveCard(chatID, n.issueKey(), card); err != nil {
+		telegramLogger.Errorf("Issue #%d. Can't save card: %s", n.Attributes.ID, err.Error())
+	}
+
+	if actionName, important := importantActions[n.Attributes.Action]; ping && edited && important && n.Comment == "" {
+		pingText := fmt.Sprintf("🔔 Issue #%d %s", n.Attributes.ID, actionName)
+		if _, err := bot.telegram.Send(&tb.Chat{ID: chatID}, pingText, &tb.SendOptions{
+			ReplyTo:           &tb.Message{ID: card.MessageID},
+			AllowWithoutReply: true,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isUneditable - message was deleted or is too old to be edited
+func isUneditable(err error) bool {
+	return err == tb.ErrCantEditMessage || strings.Contains(err.Error(), "message to edit not found")
+}
@@ -0,0 +1,85 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	storage "github.com/aberestyak/gitlab-issue-bot/internal/storage"
+	issue "github.com/aberestyak/gitlab-issue-bot/pkg/model"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	digestUsage     = "Usage: /digest hourly, /digest daily [HH:MM] or /digest off"
+	defaultDigestAt = "09:00"
+)
+
+// handleDigest - show or change user's digest setting
+func (bot *Bot) handleDigest(m *tb.Message) {
+	args := strings.Fields(m.Payload)
+	if len(args) == 0 {
+		user, _ := bot.store.GetUser(m.Sender.ID)
+		bot.reply(m, describeDigest(user.Digest)+"\n"+digestUsage)
+		return
+	}
+
+	interval, at := args[0], ""
+	switch {
+	case interval == "off" && len(args) == 1:
+		interval = ""
+	case interval == storage.DigestHourly && len(args) == 1:
+	case interval == storage.DigestDaily && len(args) <= 2:
+		at = defaultDigestAt
+		if len(args) == 2 {
+			if _, err := storage.ParseClock(args[1]); err != nil {
+				bot.reply(m, err.Error())
+				return
+			}
+			at = args[1]
+		}
+	default:
+		bot.reply(m, digestUsage)
+		return
+	}
+
+	if err := bot.store.SetDigest(m.Sender.ID, interval, at, time.Now()); err != nil {
+		telegramLogger.Errorf("Can't save digest setting for user %d: %s", m.Sender.ID, err.Error())
+		bot.reply(m, "Can't save digest setting, try again later")
+		return
+	}
+	if interval == "" {
+		// Don't lose events collected so far
+		bot.sendDigest(m.Sender.ID, time.Now())
+	}
+	user, _ := bot.store.GetUser(m.Sender.ID)
+	bot.reply(m, describeDigest(user.Digest))
+}
+
+func describeDigest(digest storage.Digest) string {
+	switch digest.Interval {
+	case storage.DigestHourly:
+		return "You receive hourly digest. Urgent events are delivered immediately"
+	case storage.DigestDaily:
+		return fmt.Sprintf("You receive daily digest at %s. Urgent events are delivered immediately", digest.At)
+	}
+	return "Digest is off, events are delivered immediately"
+}
+
+// flushDigests - send digests which are due
+func (bot *Bot) flushDigests(now time.Time) {
+	for _, telegramID := range bot.store.UsersWithDigest() {
+		user, _ := bot.store.GetUser(telegramID)
+		if !user.Receives() || !user.Digest.Due(now, user.Quiet.Location()) || user.Quiet.Active(now) {
+			continue
+		}
+		bot.sendDigest(telegramID, now)
+	}
+}
+
+// sendDigest - send user's digest if there are events in it
+func (bot *Bot) sendDigest(telegramID int, now time.Time) {
+	bot.sendSummary(telegramID, "📰 Digest", func(telegramID int) ([]issue.SummaryEvent, error) {
+		return bot.store.TakeDigestEvents(telegramID, now)
+	}, bot.store.AddDigestEvents)
+}
@@ -0,0 +1,101 @@
+package telegram
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	storage "github.com/aberestyak/gitlab-issue-bot/internal/storage"
+	utils "github.com/aberestyak/gitlab-issue-bot/utils"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+const filterUsage = "Usage: /filter include|exclude project|label <pattern>, e.g. /filter include project platform/*"
+
+// handleFilter - add project or label filter
+func (bot *Bot) handleFilter(m *tb.Message) {
+	args := strings.Fields(m.Payload)
+	if len(args) != 3 {
+		bot.reply(m, filterUsage)
+		return
+	}
+	filter := storage.Filter{Mode: args[0], Field: args[1], Pattern: args[2]}
+	if (filter.Mode != storage.FilterInclude && filter.Mode != storage.FilterExclude) ||
+		(filter.Field != storage.FilterProject && filter.Field != storage.FilterLabel) {
+		bot.reply(m, filterUsage)
+		return
+	}
+	if err := bot.store.AddFilter(m.Sender.ID, filter); err != nil {
+		telegramLogger.Errorf("Can't save filter for user %d: %s", m.Sender.ID, err.Error())
+		bot.reply(m, "Can't save filter, try again later")
+		return
+	}
+	bot.reply(m, fmt.Sprintf("Filter added: %s", filter))
+}
+
+// handleUnfilter - remove filter by its number in /filters list
+func (bot *Bot) handleUnfilter(m *tb.Message) {
+	number, err := strconv.Atoi(strings.TrimSpace(m.Payload))
+	if err != nil {
+		bot.reply(m, "Usage: /unfilter <filter number from /filters>")
+		return
+	}
+	if err := bot.store.RemoveFilter(m.Sender.ID, number-1); err != nil {
+		bot.reply(m, fmt.Sprintf("Can't remove filter: %s", err.Error()))
+		return
+	}
+	bot.reply(m, fmt.Sprintf("Filter %d removed", number))
+}
+
+// handleFilters - list user's filters
+func (bot *Bot) handleFilters(m *tb.Message) {
+	user, _ := bot.store.GetUser(m.Sender.ID)
+	if len(user.Filters) == 0 {
+		bot.reply(m, "You have no filters, all events are delivered. "+filterUsage)
+		return
+	}
+	var listBuilder strings.Builder
+	fmt.Fprintf(&listBuilder, "Your filters:\n")
+	for index, filter := range user.Filters {
+		fmt.Fprintf(&listBuilder, "%d. %s\n", index+1, filter)
+	}
+	fmt.Fprintf(&listBuilder, "Remove filter with /unfilter <number>")
+	bot.reply(m, listBuilder.String())
+}
+
+// passesFilters - check notification against user's filters. Excludes win,
+// includes of a field require at least one of them to match
+func passesFilters(filters []storage.Filter, n *notification) bool {
+	included := map[string]bool{}
+	hasIncludes := map[string]bool{}
+	for _, filter := range filters {
+		matched := filterMatches(filter, n)
+		if filter.Mode == storage.FilterExclude && matched {
+			return false
+		}
+		if filter.Mode == storage.FilterInclude {
+			hasIncludes[filter.Field] = true
+			included[filter.Field] = included[filter.Field] || matched
+		}
+	}
+	for field := range hasIncludes {
+		if !included[field] {
+			return false
+		}
+	}
+	return true
+}
+
+func filterMatches(filter storage.Filter, n *notification) bool {
+	switch filter.Field {
+	case storage.FilterProject:
+		return utils.MatchPath(filter.Pattern, n.ProjectPath)
+	case storage.FilterLabel:
+		for _, label := range n.Attributes.Labels {
+			if utils.MatchPath(filter.Pattern, label.Title) {
+				return true
+			}
+		}
+	}
+	return false
+}
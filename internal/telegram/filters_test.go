@@ -0,0 +1,41 @@
+package telegram
+
+import (
+	"testing"
+
+	storage "github.com/aberestyak/gitlab-issue-bot/internal/storage"
+	issue "github.com/aberestyak/gitlab-issue-bot/pkg/model"
+)
+
+func TestPassesFilters(t *testing.T) {
+	includeGroup := storage.Filter{Mode: storage.FilterInclude, Field: storage.FilterProject, Pattern: "group/*"}
+	excludeSub := storage.Filter{Mode: storage.FilterExclude, Field: storage.FilterProject, Pattern: "group/archive/*"}
+	includeBug := storage.Filter{Mode: storage.FilterInclude, Field: storage.FilterLabel, Pattern: "bug"}
+	tests := []struct {
+		name        string
+		filters     []storage.Filter
+		projectPath string
+		labels      []string
+		want        bool
+	}{
+		{"no filters", nil, "other/project", nil, true},
+		{"project in group", []storage.Filter{includeGroup}, "group/project", nil, true},
+		{"project in subgroup", []storage.Filter{includeGroup}, "group/sub/project", nil, true},
+		{"group with same prefix", []storage.Filter{includeGroup}, "groupX/project", nil, false},
+		{"excluded subgroup", []storage.Filter{includeGroup, excludeSub}, "group/archive/project", nil, false},
+		{"exclude wins over include", []storage.Filter{excludeSub, includeGroup}, "group/archive/project", nil, false},
+		{"includes of both fields match", []storage.Filter{includeGroup, includeBug}, "group/project", []string{"feature", "bug"}, true},
+		{"label include doesn't match", []storage.Filter{includeGroup, includeBug}, "group/project", []string{"feature"}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n := &notification{ProjectPath: test.projectPath}
+			for _, label := range test.labels {
+				n.Attributes.Labels = append(n.Attributes.Labels, issue.Labels{Title: label})
+			}
+			if got := passesFilters(test.filters, n); got != test.want {
+				t.Errorf("passesFilters() = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
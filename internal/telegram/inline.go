@@ -0,0 +1,148 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	gitlabUserAPI "github.com/aberestyak/gitlab-issue-bot/internal/gitlabAPI"
+	storage "github.com/aberestyak/gitlab-issue-bot/internal/storage"
+	issue "github.com/aberestyak/gitlab-issue-bot/pkg/model"
+	"github.com/xanzy/go-gitlab"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	inlineMinQueryLength = 3
+	inlineResultsLimit   = 20
+	// inlineCacheTTL - how long search results are reused, both by bot and by Telegram
+	inlineCacheTTL = time.Minute
+)
+
+// inlineCache - recent search results by user and query
+type inlineCache struct {
+	sync.Mutex
+	entries map[string]inlineCacheEntry
+}
+
+type inlineCacheEntry struct {
+	Issues []*gitlab.Issue
+	At     time.Time
+}
+
+func (cache *inlineCache) get(key string, now time.Time) ([]*gitlab.Issue, bool) {
+	cache.Lock()
+	defer cache.Unlock()
+	entry, found := cache.entries[key]
+	if !found || now.Sub(entry.At) > inlineCacheTTL {
+		return nil, false
+	}
+	return entry.Issues, true
+}
+
+func (cache *inlineCache) put(key string, issues []*gitlab.Issue, now time.Time) {
+	cache.Lock()
+	defer cache.Unlock()
+	for entryKey, entry := range cache.entries {
+		if now.Sub(entry.At) > inlineCacheTTL {
+			delete(cache.entries, entryKey)
+		}
+	}
+	cache.entries[key] = inlineCacheEntry{Issues: issues, At: now}
+}
+
+// handleQuery - search issues visible to user and offer them as cards
+func (bot *Bot) handleQuery(q *tb.Query) {
+	response := &tb.QueryResponse{
+		Results:    tb.Results{},
+		CacheTime:  int(inlineCacheTTL.Seconds()),
+		IsPersonal: true,
+	}
+	user, linked := bot.store.GetUser(q.From.ID)
+	query := strings.TrimSpace(q.Text)
+	switch {
+	case !linked || user.GitlabID == 0:
+		response.SwitchPMText = "Link your GitLab account to search issues"
+		response.SwitchPMParameter = "link"
+	case len([]rune(query)) >= inlineMinQueryLength:
+		issues, err := bot.searchIssues(user, query)
+		if err != nil {
+			telegramLogger.Errorf("Can't search issues for %s: %s", user.GitlabUsername, err.Error())
+			// Empty answer stops client's spinner. Zero cache time is omitted and means
+			// Telegram's default, so failure is cached for a second only
+			response.CacheTime = 1
+			break
+		}
+		for _, gitlabIssue := range issues {
+			response.Results = append(response.Results, inlineResult(gitlabIssue))
+		}
+	}
+	if err := bot.telegram.Answer(q, response); err != nil {
+		telegramLogger.Errorf("Can't answer inline query: %s", err.Error())
+	}
+}
+
+// searchIssues - search issues user is permitted to see, cached for a while
+func (bot *Bot) searchIssues(user storage.User, query string) ([]*gitlab.Issue, error) {
+	now := time.Now()
+	cacheKey := fmt.Sprintf("%d:%s", user.TelegramID, strings.ToLower(query))
+	if issues, found := bot.inline.get(cacheKey, now); found {
+		return issues, nil
+	}
+	found, _, err := bot.gitlab.Search.Issues(query, &gitlab.SearchOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 50},
+	}, bot.actAs(user)...)
+	if err != nil {
+		return nil, err
+	}
+	var issues []*gitlab.Issue
+	accessLevels := map[int]gitlab.AccessLevelValue{}
+	for _, gitlabIssue := range found {
+		if len(issues) == inlineResultsLimit {
+			break
+		}
+		// Search on behalf of user returns only issues visible to him
+		if !bot.conf().GitlabSudo && !bot.canSee(user, gitlabIssue, accessLevels) {
+			continue
+		}
+		issues = append(issues, gitlabIssue)
+	}
+	bot.inline.put(cacheKey, issues, now)
+	return issues, nil
+}
+
+// canSee - check user may see issue found with bot's token. Only project members
+// are offered issues, even of public projects
+func (bot *Bot) canSee(user storage.User, gitlabIssue *gitlab.Issue, accessLevels map[int]gitlab.AccessLevelValue) bool {
+	accessLevel, checked := accessLevels[gitlabIssue.ProjectID]
+	if !checked {
+		var err error
+		accessLevel, err = gitlabUserAPI.GetAccessLevel(gitlabIssue.ProjectID, user.GitlabID, bot.gitlab)
+		if err != nil {
+			telegramLogger.Errorf("Can't check access of %s to project %d: %s", user.GitlabUsername, gitlabIssue.ProjectID, err.Error())
+			accessLevel = gitlab.NoPermissions
+		}
+		accessLevels[gitlabIssue.ProjectID] = accessLevel
+	}
+	return canRead(accessLevel, false, user.GitlabID, issue.AttributesFromGitlab(gitlabIssue), false)
+}
+
+// inlineResult - issue card to insert into chat
+func inlineResult(gitlabIssue *gitlab.Issue) *tb.ArticleResult {
+	card := issue.IssueCard{Attributes: issue.AttributesFromGitlab(gitlabIssue)}
+	result := &tb.ArticleResult{
+		Title:       fmt.Sprintf("%s#%d %s", projectPathFromURL(gitlabIssue.WebURL), gitlabIssue.IID, gitlabIssue.Title),
+		Description: fmt.Sprintf("%s, %s", gitlabIssue.State, strings.Join(gitlabIssue.Labels, ", ")),
+		URL:         gitlabIssue.WebURL,
+		HideURL:     true,
+	}
+	result.SetResultID(fmt.Sprintf("%d-%d", gitlabIssue.ProjectID, gitlabIssue.IID))
+	result.SetContent(&tb.InputTextMessageContent{
+		Text:           card.BeautifyNotification(),
+		ParseMode:      tb.ModeMarkdownV2,
+		DisablePreview: true,
+	})
+	result.ReplyMarkup = &tb.InlineKeyboardMarkup{InlineKeyboard: [][]tb.InlineButton{{{Text: "🔗 Open", URL: gitlabIssue.WebURL}}}}
+	return result
+}
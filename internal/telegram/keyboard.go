@@ -0,0 +1,105 @@
+package telegram
+
+import (
+	"strconv"
+
+	issue "github.com/aberestyak/gitlab-issue-bot/pkg/model"
+	log "github.com/sirupsen/logrus"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+const labelsPerRow = 3
+
+// dueDateOptions - due date buttons, days from today
+var dueDateOptions = []struct {
+	Text string
+	Days int
+}{
+	{"Today", 0},
+	{"Tomorrow", 1},
+	{"In a week", 7},
+	{"In two weeks", 14},
+}
+
+var keyboardLogger = log.WithFields(log.Fields{
+	"component": "Keyboard",
+})
+
+// IssueKeyboard - build inline keyboard with actions for issue notification
+func IssueKeyboard(secret []byte, labelShortlist []string, attributes issue.Attibutes) *tb.ReplyMarkup {
+	markup := &tb.ReplyMarkup{}
+	if attributes.URL != "" {
+		markup.InlineKeyboard = append(markup.InlineKeyboard, []tb.InlineButton{
+			{Text: "🔗 Open in GitLab", URL: attributes.URL},
+		})
+	}
+
+	var stateButton tb.InlineButton
+	if attributes.State == "closed" {
+		stateButton = callbackButton(secret, "♾ Reopen", CallbackData{Action: ActionReopen}, attributes)
+	} else {
+		stateButton = callbackButton(secret, "🚫 Close", CallbackData{Action: ActionClose}, attributes)
+	}
+	markup.InlineKeyboard = append(markup.InlineKeyboard, []tb.InlineButton{
+		stateButton,
+		callbackButton(secret, "🙋 Assign to me", CallbackData{Action: ActionAssignMe}, attributes),
+		callbackButton(secret, "📅 Due date", CallbackData{Action: ActionDueMenu}, attributes),
+	})
+
+	var labelsRow []tb.InlineButton
+	for _, label := range labelShortlist {
+		if hasLabel(attributes.Labels, label) {
+			continue
+		}
+		labelsRow = append(labelsRow, callbackButton(secret, "🏷 "+label, CallbackData{Action: ActionAddLabel, Arg: labelHash(label)}, attributes))
+		if len(labelsRow) == labelsPerRow {
+			markup.InlineKeyboard = append(markup.InlineKeyboard, labelsRow)
+			labelsRow = nil
+		}
+	}
+	if len(labelsRow) > 0 {
+		markup.InlineKeyboard = append(markup.InlineKeyboard, labelsRow)
+	}
+
+	markup.InlineKeyboard = append(markup.InlineKeyboard, []tb.InlineButton{
+		callbackButton(secret, "🔕 Mute this issue", CallbackData{Action: ActionMute}, attributes),
+	})
+	return markup
+}
+
+// DueDateKeyboard - build inline keyboard to choose issue due date
+func DueDateKeyboard(secret []byte, attributes issue.Attibutes) *tb.ReplyMarkup {
+	markup := &tb.ReplyMarkup{}
+	var row []tb.InlineButton
+	for _, option := range dueDateOptions {
+		row = append(row, callbackButton(secret, option.Text, CallbackData{Action: ActionDueDate, Arg: strconv.Itoa(option.Days)}, attributes))
+		if len(row) == 2 {
+			markup.InlineKeyboard = append(markup.InlineKeyboard, row)
+			row = nil
+		}
+	}
+	markup.InlineKeyboard = append(markup.InlineKeyboard, []tb.InlineButton{
+		callbackButton(secret, "⬅️ Back", CallbackData{Action: ActionBack}, attributes),
+	})
+	return markup
+}
+
+// callbackButton - create button with signed callback data for the issue
+func callbackButton(secret []byte, text string, data CallbackData, attributes issue.Attibutes) tb.InlineButton {
+	data.ProjectID = attributes.ProjectID
+	data.IssueIID = attributes.ID
+	encoded, err := data.Encode(secret)
+	if err != nil {
+		keyboardLogger.Errorf("Can't encode callback data: %s", err.Error())
+	}
+	return tb.InlineButton{Text: text, Data: encoded}
+}
+
+func hasLabel(labels []issue.Labels, title string) bool {
+	for _, label := range labels {
+		if label.Title == title {
+			return true
+		}
+	}
+	return false
+}
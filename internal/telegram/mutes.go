@@ -0,0 +1,187 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	config "github.com/aberestyak/gitlab-issue-bot/internal/config"
+	storage "github.com/aberestyak/gitlab-issue-bot/internal/storage"
+	issue "github.com/aberestyak/gitlab-issue-bot/pkg/model"
+	"github.com/xanzy/go-gitlab"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+var (
+	issueURLRegexp   = regexp.MustCompile(`^https?://[^/]+/(.+?)(?:/-)?/issues/(\d+)`)
+	issueShortRegexp = regexp.MustCompile(`^#?(.+)#(\d+)$`)
+	snoozeRegexp     = regexp.MustCompile(`^(\d+)([mhdw])$`)
+	snoozeUnits      = map[string]time.Duration{
+		"m": time.Minute,
+		"h": time.Hour,
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	}
+)
+
+// handleMute - mute issue until unmuted
+func (bot *Bot) handleMute(m *tb.Message) {
+	args := strings.Fields(m.Payload)
+	if len(args) != 1 {
+		bot.reply(m, "Usage: /mute <issue url or group/project#iid>")
+		return
+	}
+	bot.muteIssue(m, args[0], time.Time{})
+}
+
+// handleSnooze - mute issue for some time
+func (bot *Bot) handleSnooze(m *tb.Message) {
+	args := strings.Fields(m.Payload)
+	if len(args) != 2 {
+		bot.reply(m, "Usage: /snooze <issue url or group/project#iid> <duration>, e.g. /snooze group/project#12 2d")
+		return
+	}
+	duration, err := parseSnooze(args[1])
+	if err != nil {
+		bot.reply(m, err.Error())
+		return
+	}
+	bot.muteIssue(m, args[0], time.Now().Add(duration))
+}
+
+func (bot *Bot) muteIssue(m *tb.Message, issueRef string, until time.Time) {
+	gitlabIssue, instance, err := bot.resolveIssue(issueRef)
+	if err != nil {
+		bot.reply(m, fmt.Sprintf("Can't find issue %s: %s", issueRef, err.Error()))
+		return
+	}
+	mute := storage.Mute{Until: until, IssueURL: gitlabIssue.WebURL}
+	if err := bot.store.MuteIssue(m.Sender.ID, storage.InstanceIssueKey(instance, gitlabIssue.ProjectID, gitlabIssue.IID), mute, time.Now()); err != nil {
+		telegramLogger.Errorf("Can't mute issue for user %d: %s", m.Sender.ID, err.Error())
+		bot.reply(m, "Can't mute issue, try again later")
+		return
+	}
+	if until.IsZero() {
+		bot.reply(m, fmt.Sprintf("Issue %s is muted. See /muted to unmute", gitlabIssue.WebURL))
+	} else {
+		bot.reply(m, fmt.Sprintf("Issue %s is snoozed till %s", gitlabIssue.WebURL, until.Format(time.RFC1123)))
+	}
+}
+
+// handleMuted - list muted issues with unmute buttons
+func (bot *Bot) handleMuted(m *tb.Message) {
+	mutedIssues := bot.store.MutedIssues(m.Sender.ID, time.Now())
+	if len(mutedIssues) == 0 {
+		bot.reply(m, "You have no muted issues")
+		return
+	}
+	var listBuilder strings.Builder
+	markup := &tb.ReplyMarkup{}
+	fmt.Fprintf(&listBuilder, "Muted issues:\n")
+	for index, mutedIssue := range mutedIssues {
+		fmt.Fprintf(&listBuilder, "%d. %s", index+1, mutedIssue.IssueURL)
+		if !mutedIssue.Until.IsZero() {
+			fmt.Fprintf(&listBuilder, " (till %s)", mutedIssue.Until.Format(time.RFC1123))
+		}
+		fmt.Fprintf(&listBuilder, "\n")
+		attributes := issue.Attibutes{ProjectID: mutedIssue.ProjectID, ID: mutedIssue.IssueIID}
+		markup.InlineKeyboard = append(markup.InlineKeyboard, []tb.InlineButton{
+			callbackButton(bot.conf().CallbackSecret, fmt.Sprintf("🔔 Unmute %d", index+1), CallbackData{Action: ActionUnmute, Arg: mutedIssue.Instance}, attributes),
+		})
+	}
+	if _, err := bot.telegram.Send(m.Chat, listBuilder.String(), markup, tb.NoPreview); err != nil {
+		telegramLogger.Errorf("Error while send message: %s", err.Error())
+	}
+}
+
+// muteFromCallback - mute issue for user pressed the button
+func (bot *Bot) muteFromCallback(c *tb.Callback, data CallbackData) {
+	// Instance of issue is known from message only
+	if c.Message == nil || c.Message.Chat == nil {
+		bot.respond(c, "Can't mute issue here", true)
+		return
+	}
+	instance, known := bot.messageInstance(c.Message)
+	if !known {
+		bot.respond(c, "This message is too old, mute the issue with /mute <issue url>", true)
+		return
+	}
+	mute := storage.Mute{}
+	if ref, found := bot.store.GetMessage(c.Message.Chat.ID, c.Message.ID); found {
+		mute.IssueURL = ref.IssueURL
+	}
+	if err := bot.store.MuteIssue(c.Sender.ID, storage.InstanceIssueKey(instance, data.ProjectID, data.IssueIID), mute, time.Now()); err != nil {
+		telegramLogger.Errorf("Can't mute issue for user %d: %s", c.Sender.ID, err.Error())
+		bot.respond(c, "Can't mute issue", true)
+		return
+	}
+	bot.respond(c, fmt.Sprintf("Issue #%d muted. See /muted to unmute", data.IssueIID), false)
+}
+
+// unmuteFromCallback - unmute issue from /muted list
+func (bot *Bot) unmuteFromCallback(c *tb.Callback, data CallbackData) {
+	if err := bot.store.UnmuteIssue(c.Sender.ID, storage.InstanceIssueKey(data.Arg, data.ProjectID, data.IssueIID)); err != nil {
+		telegramLogger.Errorf("Can't unmute issue for user %d: %s", c.Sender.ID, err.Error())
+		bot.respond(c, "Can't unmute issue", true)
+		return
+	}
+	bot.respond(c, fmt.Sprintf("Issue #%d unmuted", data.IssueIID), false)
+}
+
+// resolveIssue - find issue by its URL or group/project#iid reference. URL is looked up
+// on instance with the same host, reference on default instance
+func (bot *Bot) resolveIssue(issueRef string) (*gitlab.Issue, string, error) {
+	instance := config.DefaultInstance
+	match := issueURLRegexp.FindStringSubmatch(issueRef)
+	if match != nil {
+		var found bool
+		if instance, found = bot.instanceOfURL(issueRef); !found {
+			return nil, "", errors.New("it's not on known GitLab")
+		}
+	} else {
+		match = issueShortRegexp.FindStringSubmatch(issueRef)
+	}
+	if match == nil {
+		return nil, "", errors.New("use issue URL or group/project#iid")
+	}
+	issueIID, err := strconv.Atoi(match[2])
+	if err != nil {
+		return nil, "", err
+	}
+	gitlabIssue, _, err := bot.client(instance).Issues.GetIssue(match[1], issueIID)
+	if err != nil {
+		return nil, "", err
+	}
+	return gitlabIssue, instance, nil
+}
+
+// instanceOfURL - name of configured instance with the same host as URL
+func (bot *Bot) instanceOfURL(rawURL string) (string, bool) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	for _, instance := range bot.conf().Instances {
+		if instanceURL, err := url.Parse(instance.URL); err == nil && strings.EqualFold(instanceURL.Host, parsed.Host) {
+			return instance.Name, true
+		}
+	}
+	return "", false
+}
+
+// parseSnooze - parse duration like 30m, 4h, 2d or 1w
+func parseSnooze(value string) (time.Duration, error) {
+	match := snoozeRegexp.FindStringSubmatch(value)
+	if match == nil {
+		return 0, fmt.Errorf("wrong duration %s, use e.g. 30m, 4h, 2d or 1w", value)
+	}
+	count, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(count) * snoozeUnits[match[2]], nil
+}
@@ -0,0 +1,224 @@
+package telegram
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	storage "github.com/aberestyak/gitlab-issue-bot/internal/storage"
+	issue "github.com/aberestyak/gitlab-issue-bot/pkg/model"
+	utils "github.com/aberestyak/gitlab-issue-bot/utils"
+	"github.com/xanzy/go-gitlab"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	myItemsPerPage = 10
+	// Items without priority label go after prioritized ones
+	noPriority = 1 << 30
+)
+
+var (
+	projectPathRegexp   = regexp.MustCompile(`^https?://[^/]+/(.+?)(?:/-)?/(?:issues|merge_requests)/\d+`)
+	priorityLabelRegexp = regexp.MustCompile(`^priority::(\d+)$`)
+)
+
+// workItem - issue or merge request waiting for user
+type workItem struct {
+	ProjectPath string
+	Reference   string
+	Title       string
+	URL         string
+	DueDate     *time.Time
+	Priority    int
+	IsMR        bool
+}
+
+// handleMy - list user's open issues and merge requests awaiting review
+func (bot *Bot) handleMy(m *tb.Message) {
+	// List includes confidential issues
+	if !m.Private() {
+		bot.reply(m, "Your issues are listed only in private chat with bot")
+		return
+	}
+	user, linked := bot.store.GetUser(m.Sender.ID)
+	if !linked || user.GitlabID == 0 {
+		bot.reply(m, "Link your GitLab account first: /link <gitlab username>")
+		return
+	}
+	text, markup, err := bot.myPage(user, 0)
+	if err != nil {
+		telegramLogger.Errorf("Can't list work items of user %s: %s", user.GitlabUsername, err.Error())
+		bot.reply(m, "Can't get your issues from GitLab, try again later")
+		return
+	}
+	if _, err := bot.telegram.Send(m.Chat, text, &tb.SendOptions{ParseMode: tb.ModeMarkdownV2, ReplyMarkup: markup, DisableWebPagePreview: true}); err != nil {
+		telegramLogger.Errorf("Error while send message: %s", err.Error())
+	}
+}
+
+// myPageFromCallback - switch /my page
+func (bot *Bot) myPageFromCallback(c *tb.Callback, data CallbackData) {
+	if !senderChat(c) {
+		bot.respond(c, "This list belongs to another user", true)
+		return
+	}
+	user, linked := bot.store.GetUser(c.Sender.ID)
+	page, err := strconv.Atoi(data.Arg)
+	if !linked || err != nil {
+		bot.respond(c, "", false)
+		return
+	}
+	text, markup, err := bot.myPage(user, page)
+	if err != nil {
+		telegramLogger.Errorf("Can't list work items of user %s: %s", user.GitlabUsername, err.Error())
+		bot.respond(c, "Can't get your issues from GitLab", true)
+		return
+	}
+	if _, err := bot.telegram.Edit(c.Message, text, &tb.SendOptions{ParseMode: tb.ModeMarkdownV2, ReplyMarkup: markup, DisableWebPagePreview: true}); err != nil && !isNotModified(err) {
+		telegramLogger.Errorf("Can't update message: %s", err.Error())
+	}
+	bot.respond(c, "", false)
+}
+
+// myPage - render page of user's work items with pagination buttons
+func (bot *Bot) myPage(user storage.User, page int) (string, *tb.ReplyMarkup, error) {
+	items, err := bot.myWorkItems(user)
+	if err != nil {
+		return "", nil, err
+	}
+	pages := (len(items) + myItemsPerPage - 1) / myItemsPerPage
+	if page >= pages {
+		page = pages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+
+	var pageBuilder strings.Builder
+	pageBuilder.Grow(32)
+	if len(items) == 0 {
+		fmt.Fprintf(&pageBuilder, "🎉 *Nothing is waiting for you*\n")
+		return pageBuilder.String(), nil, nil
+	}
+	fmt.Fprintf(&pageBuilder, "📌 *Your open issues and merge requests* \\(%d/%d\\)\n", page+1, pages)
+	end := (page + 1) * myItemsPerPage
+	if end > len(items) {
+		end = len(items)
+	}
+	var projectPath string
+	for _, item := range items[page*myItemsPerPage : end] {
+		if item.ProjectPath != projectPath {
+			projectPath = item.ProjectPath
+			fmt.Fprintf(&pageBuilder, "\n*%s*\n", utils.SanitizeTelegramString(projectPath))
+		}
+		icon := "🐞"
+		if item.IsMR {
+			icon = "🔀"
+		}
+		fmt.Fprintf(&pageBuilder, "  ◦ %s [%s](%s) %s", icon, utils.SanitizeTelegramString(item.Reference), item.URL, utils.SanitizeTelegramString(item.Title))
+		if item.DueDate != nil {
+			fmt.Fprintf(&pageBuilder, " 📅 %s", utils.SanitizeTelegramString(item.DueDate.Format("2006-01-02")))
+		}
+		fmt.Fprintf(&pageBuilder, "\n")
+	}
+
+	markup := &tb.ReplyMarkup{}
+	var row []tb.InlineButton
+	if page > 0 {
+		row = append(row, callbackButton(bot.conf().CallbackSecret, "◀️ Prev", CallbackData{Action: ActionMyPage, Arg: strconv.Itoa(page - 1)}, issue.Attibutes{}))
+	}
+	if page < pages-1 {
+		row = append(row, callbackButton(bot.conf().CallbackSecret, "Next ▶️", CallbackData{Action: ActionMyPage, Arg: strconv.Itoa(page + 1)}, issue.Attibutes{}))
+	}
+	if len(row) > 0 {
+		markup.InlineKeyboard = append(markup.InlineKeyboard, row)
+	}
+	return pageBuilder.String(), markup, nil
+}
+
+// myWorkItems - open issues assigned to user and merge requests awaiting his review
+func (bot *Bot) myWorkItems(user storage.User) ([]workItem, error) {
+	var items []workItem
+	issues, _, err := bot.gitlab.Issues.ListIssues(&gitlab.ListIssuesOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 100},
+		State:       gitlab.String("opened"),
+		Scope:       gitlab.String("all"),
+		AssigneeID:  gitlab.Int(user.GitlabID),
+	}, bot.actAs(user)...)
+	if err != nil {
+		return nil, err
+	}
+	for _, gitlabIssue := range issues {
+		item := workItem{
+			ProjectPath: projectPathFromURL(gitlabIssue.WebURL),
+			Reference:   fmt.Sprintf("#%d", gitlabIssue.IID),
+			Title:       gitlabIssue.Title,
+			URL:         gitlabIssue.WebURL,
+			Priority:    priorityFromLabels(gitlabIssue.Labels),
+		}
+		if gitlabIssue.DueDate != nil {
+			dueDate := time.Time(*gitlabIssue.DueDate)
+			item.DueDate = &dueDate
+		}
+		items = append(items, item)
+	}
+
+	mergeRequests, _, err := bot.gitlab.MergeRequests.ListMergeRequests(&gitlab.ListMergeRequestsOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 100},
+		State:       gitlab.String("opened"),
+		Scope:       gitlab.String("all"),
+		ReviewerID:  gitlab.Int(user.GitlabID),
+	}, bot.actAs(user)...)
+	if err != nil {
+		return nil, err
+	}
+	for _, mergeRequest := range mergeRequests {
+		items = append(items, workItem{
+			ProjectPath: projectPathFromURL(mergeRequest.WebURL),
+			Reference:   fmt.Sprintf("!%d", mergeRequest.IID),
+			Title:       mergeRequest.Title,
+			URL:         mergeRequest.WebURL,
+			Priority:    priorityFromLabels(mergeRequest.Labels),
+			IsMR:        true,
+		})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].ProjectPath != items[j].ProjectPath {
+			return items[i].ProjectPath < items[j].ProjectPath
+		}
+		if (items[i].DueDate == nil) != (items[j].DueDate == nil) {
+			return items[i].DueDate != nil
+		}
+		if items[i].DueDate != nil && !items[i].DueDate.Equal(*items[j].DueDate) {
+			return items[i].DueDate.Before(*items[j].DueDate)
+		}
+		return items[i].Priority < items[j].Priority
+	})
+	return items, nil
+}
+
+// projectPathFromURL - get project path from issue or merge request URL
+func projectPathFromURL(webURL string) string {
+	if match := projectPathRegexp.FindStringSubmatch(webURL); match != nil {
+		return match[1]
+	}
+	return webURL
+}
+
+// priorityFromLabels - get priority from priority::N label, lower is more important
+func priorityFromLabels(labels gitlab.Labels) int {
+	priority := noPriority
+	for _, label := range labels {
+		if match := priorityLabelRegexp.FindStringSubmatch(label); match != nil {
+			if value, err := strconv.Atoi(match[1]); err == nil && value < priority {
+				priority = value
+			}
+		}
+	}
+	return priority
+}
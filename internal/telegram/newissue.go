@@ -0,0 +1,285 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	gitlabUserAPI "github.com/aberestyak/gitlab-issue-bot/internal/gitlabAPI"
+	storage "github.com/aberestyak/gitlab-issue-bot/internal/storage"
+	"github.com/xanzy/go-gitlab"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+// conversationTimeout - unfinished conversation is dropped after this period of silence
+const conversationTimeout = 10 * time.Minute
+
+// Steps of issue creation conversation
+const (
+	stepProject = iota
+	stepTitle
+	stepDescription
+	stepLabels
+	stepAssignee
+)
+
+// conversation - state of /new dialog with user. Stored by value, handlers change their copy
+// and save it back with advance, so concurrent messages can't change it halfway
+type conversation struct {
+	Started      time.Time
+	Step         int
+	ProjectID    int
+	ProjectPath  string
+	Title        string
+	Description  string
+	Labels       []string
+	LastActivity time.Time
+}
+
+// conversations - in-memory dialogs by telegram user ID
+type conversations struct {
+	sync.Mutex
+	byUser map[int]conversation
+}
+
+func (dialogs *conversations) get(telegramID int) (conversation, bool) {
+	dialogs.Lock()
+	defer dialogs.Unlock()
+	dialog, found := dialogs.byUser[telegramID]
+	return dialog, found
+}
+
+func (dialogs *conversations) set(telegramID int, dialog conversation) {
+	dialogs.Lock()
+	defer dialogs.Unlock()
+	dialogs.byUser[telegramID] = dialog
+}
+
+// touch - prolong dialog timeout
+func (dialogs *conversations) touch(telegramID int, now time.Time) {
+	dialogs.Lock()
+	defer dialogs.Unlock()
+	if dialog, found := dialogs.byUser[telegramID]; found {
+		dialog.LastActivity = now
+		dialogs.byUser[telegramID] = dialog
+	}
+}
+
+// advance - replace dialog with next state, remove it if next is nil. Fails if dialog
+// was changed, cancelled or restarted since current was read
+func (dialogs *conversations) advance(telegramID int, current conversation, next *conversation) bool {
+	dialogs.Lock()
+	defer dialogs.Unlock()
+	stored, found := dialogs.byUser[telegramID]
+	if !found || !stored.Started.Equal(current.Started) || stored.Step != current.Step {
+		return false
+	}
+	if next == nil {
+		delete(dialogs.byUser, telegramID)
+	} else {
+		dialogs.byUser[telegramID] = *next
+	}
+	return true
+}
+
+func (dialogs *conversations) remove(telegramID int) bool {
+	dialogs.Lock()
+	defer dialogs.Unlock()
+	_, found := dialogs.byUser[telegramID]
+	delete(dialogs.byUser, telegramID)
+	return found
+}
+
+// expired - remove and return users whose dialogs timed out
+func (dialogs *conversations) expired(now time.Time) []int {
+	dialogs.Lock()
+	defer dialogs.Unlock()
+	var telegramIDs []int
+	for telegramID, dialog := range dialogs.byUser {
+		if now.Sub(dialog.LastActivity) > conversationTimeout {
+			telegramIDs = append(telegramIDs, telegramID)
+			delete(dialogs.byUser, telegramID)
+		}
+	}
+	return telegramIDs
+}
+
+// handleNew - start issue creation dialog
+func (bot *Bot) handleNew(m *tb.Message) {
+	if !m.Private() {
+		bot.reply(m, "Issues can be created only in private chat with bot")
+		return
+	}
+	user, linked := bot.store.GetUser(m.Sender.ID)
+	if !linked || user.GitlabID == 0 {
+		bot.reply(m, "Link your GitLab account first: /link <gitlab username>")
+		return
+	}
+	now := time.Now()
+	bot.dialogs.set(m.Sender.ID, conversation{Started: now, Step: stepProject, LastActivity: now})
+
+	markup := &tb.ReplyMarkup{ResizeReplyKeyboard: true, OneTimeKeyboard: true}
+	for _, projectPath := range user.RecentProjects {
+		markup.ReplyKeyboard = append(markup.ReplyKeyboard, []tb.ReplyButton{{Text: projectPath}})
+	}
+	if len(markup.ReplyKeyboard) == 0 {
+		markup.ReplyKeyboardRemove = true
+	}
+	bot.ask(m.Chat, "Which project? Send its path, e.g. group/project. /cancel to stop", markup)
+}
+
+// handleCancel - drop issue creation dialog
+func (bot *Bot) handleCancel(m *tb.Message) {
+	// Dialog runs in private chat only
+	if !m.Private() {
+		return
+	}
+	if !bot.dialogs.remove(m.Sender.ID) {
+		bot.reply(m, "Nothing to cancel")
+		return
+	}
+	bot.ask(m.Chat, "Issue creation cancelled", &tb.ReplyMarkup{ReplyKeyboardRemove: true})
+}
+
+// handleSkip - skip optional step of issue creation dialog
+func (bot *Bot) handleSkip(m *tb.Message) {
+	if !m.Private() {
+		return
+	}
+	dialog, found := bot.dialogs.get(m.Sender.ID)
+	if !found {
+		bot.reply(m, "Nothing to skip")
+		return
+	}
+	if dialog.Step < stepDescription {
+		bot.reply(m, "This step can't be skipped, /cancel to stop")
+		return
+	}
+	bot.continueConversation(m, dialog, "")
+}
+
+// continueConversation - handle user's answer to current step
+func (bot *Bot) continueConversation(m *tb.Message, dialog conversation, answer string) {
+	user, linked := bot.store.GetUser(m.Sender.ID)
+	if !linked || user.GitlabID == 0 {
+		bot.dialogs.remove(m.Sender.ID)
+		bot.reply(m, "Link your GitLab account first: /link <gitlab username>")
+		return
+	}
+	bot.dialogs.touch(m.Sender.ID, time.Now())
+	answer = strings.TrimSpace(answer)
+	next := dialog
+	next.LastActivity = time.Now()
+
+	switch dialog.Step {
+	case stepProject:
+		project, _, err := bot.gitlab.Projects.GetProject(answer, nil, bot.actAs(user)...)
+		if err != nil {
+			bot.reply(m, fmt.Sprintf("Can't find project %s, try again or /cancel", answer))
+			return
+		}
+		accessLevel, err := gitlabUserAPI.GetAccessLevel(project.ID, user.GitlabID, bot.gitlab)
+		if err != nil || accessLevel < gitlab.GuestPermissions {
+			bot.reply(m, fmt.Sprintf("You can't create issues in %s, choose another project or /cancel", project.PathWithNamespace))
+			return
+		}
+		next.ProjectID = project.ID
+		next.ProjectPath = project.PathWithNamespace
+		next.Step = stepTitle
+		if !bot.dialogs.advance(m.Sender.ID, dialog, &next) {
+			return
+		}
+		bot.ask(m.Chat, "Issue title?", &tb.ReplyMarkup{ReplyKeyboardRemove: true})
+	case stepTitle:
+		if answer == "" {
+			bot.reply(m, "Title can't be empty")
+			return
+		}
+		next.Title = answer
+		next.Step = stepDescription
+		if !bot.dialogs.advance(m.Sender.ID, dialog, &next) {
+			return
+		}
+		bot.reply(m, "Description? /skip to leave it empty")
+	case stepDescription:
+		next.Description = answer
+		next.Step = stepLabels
+		if !bot.dialogs.advance(m.Sender.ID, dialog, &next) {
+			return
+		}
+		labelsHint := "Labels separated by comma? /skip for none"
+		if len(bot.conf().LabelShortlist) > 0 {
+			labelsHint += fmt.Sprintf(", e.g. %s", strings.Join(bot.conf().LabelShortlist, ", "))
+		}
+		bot.reply(m, labelsHint)
+	case stepLabels:
+		next.Labels = nil
+		for _, label := range strings.Split(answer, ",") {
+			if label = strings.TrimSpace(label); label != "" {
+				next.Labels = append(next.Labels, label)
+			}
+		}
+		next.Step = stepAssignee
+		if !bot.dialogs.advance(m.Sender.ID, dialog, &next) {
+			return
+		}
+		bot.reply(m, "Assignee's GitLab username? Send \"me\" to assign yourself, /skip for nobody")
+	case stepAssignee:
+		var assigneeIDs []int
+		switch answer {
+		case "":
+		case "me":
+			assigneeIDs = []int{user.GitlabID}
+		default:
+			assignee, err := gitlabUserAPI.GetUserByUsername(strings.TrimPrefix(answer, "@"), bot.gitlab)
+			if err != nil || assignee == nil {
+				bot.reply(m, fmt.Sprintf("Can't find GitLab user %s, try again, /skip or /cancel", answer))
+				return
+			}
+			assigneeIDs = []int{assignee.ID}
+		}
+		// Issue is created once even if answer is sent twice
+		if !bot.dialogs.advance(m.Sender.ID, dialog, nil) {
+			return
+		}
+		bot.createIssue(m, user, dialog, assigneeIDs)
+	}
+}
+
+// createIssue - create issue collected in dialog
+func (bot *Bot) createIssue(m *tb.Message, user storage.User, dialog conversation, assigneeIDs []int) {
+	options := &gitlab.CreateIssueOptions{
+		Title:       gitlab.String(dialog.Title),
+		AssigneeIDs: assigneeIDs,
+		Labels:      dialog.Labels,
+	}
+	if dialog.Description != "" || !bot.conf().GitlabSudo {
+		options.Description = gitlab.String(bot.commentBody(user, dialog.Description))
+	}
+	gitlabIssue, _, err := bot.gitlab.Issues.CreateIssue(dialog.ProjectID, options, bot.actAs(user)...)
+	if err != nil {
+		telegramLogger.Errorf("Can't create issue in project %s for %s: %s", dialog.ProjectPath, user.GitlabUsername, err.Error())
+		bot.reply(m, "Can't create issue in GitLab, try again later")
+		return
+	}
+	telegramLogger.Infof("Issue #%d in project %s created by %s", gitlabIssue.IID, dialog.ProjectPath, user.GitlabUsername)
+	if err := bot.store.AddRecentProject(m.Sender.ID, dialog.ProjectPath); err != nil {
+		telegramLogger.Errorf("Can't save recent project of user %d: %s", m.Sender.ID, err.Error())
+	}
+	bot.reply(m, fmt.Sprintf("Issue created: %s", gitlabIssue.WebURL))
+}
+
+// expireConversations - drop dialogs abandoned by users
+func (bot *Bot) expireConversations(now time.Time) {
+	for _, telegramID := range bot.dialogs.expired(now) {
+		bot.ask(&tb.Chat{ID: int64(telegramID)}, "Issue creation cancelled due to inactivity", &tb.ReplyMarkup{ReplyKeyboardRemove: true})
+	}
+}
+
+// ask - send question with reply keyboard
+func (bot *Bot) ask(chat *tb.Chat, text string, markup *tb.ReplyMarkup) {
+	if _, err := bot.telegram.Send(chat, text, markup); err != nil {
+		telegramLogger.Errorf("Error while send message: %s", err.Error())
+	}
+}
@@ -0,0 +1,254 @@
+package telegram
+
+import (
+	"errors"
+	"time"
+
+	storage "github.com/aberestyak/gitlab-issue-bot/internal/storage"
+	issue "github.com/aberestyak/gitlab-issue-bot/pkg/model"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+// notification - issue event rendered for telegram
+type notification struct {
+	Text          string
+	Kind          string
+	ProjectPath   string
+	LabelsOnly    bool
+	Reassigned    bool
+	Attributes    issue.Attibutes
+	DiscussionID  string
+	Comment       string
+	CommentAuthor string
+	Keyboard      *tb.ReplyMarkup
+	Priority      string
+	Alert         bool
+	Instance      string
+	// PrivateProject - project issues are visible to its members only
+	PrivateProject bool
+	// PublicProject - project issues are visible to everyone
+	PublicProject bool
+	// InternalNote - comment is visible to project reporters only
+	InternalNote bool
+	// Redacted - confidential content is stripped, text has only issue number and link
+	Redacted bool
+}
+
+// isConfidential - notification is about confidential issue or internal comment
+func (n *notification) isConfidential() bool {
+	return n.Attributes.Confidential || n.InternalNote
+}
+
+// issueKey - key of the issue notification is about
+func (n *notification) issueKey() string {
+	return storage.InstanceIssueKey(n.Instance, n.Attributes.ProjectID, n.Attributes.ID)
+}
+
+// Notify - send notification about issue event to all involved users and routed chats
+func (bot *Bot) Notify(event issue.Issue) {
+	if event.SystemHook != nil {
+		bot.handleSystemHook(event.Instance, event.SystemHook)
+		return
+	}
+	bot.stats.countEvent()
+	n, err := bot.render(event)
+	if err != nil {
+		telegramLogger.Errorln(err.Error())
+		return
+	}
+	issueID := n.Attributes.ID
+	if n.Kind == "close" {
+		bot.resolveAlerts(n)
+	}
+	defer bot.notifyChats(n)
+
+	botUsers, err := event.CreateUsersList(bot.client(event.Instance))
+	if err != nil {
+		telegramLogger.Errorf("Issue #%d. Can't create users list: %s", issueID, err.Error())
+		return
+	}
+
+	now := time.Now()
+	for _, botUser := range botUsers {
+		if botUser.TelegramID == 0 {
+			telegramLogger.Infof("Issue #%d. Can't send notifaction sent to user %s", issueID, botUser.Name)
+			if err := bot.store.RecordUnmapped(botUser.Name, now); err != nil {
+				telegramLogger.Errorf("Can't record unmapped user %s: %s", botUser.Name, err.Error())
+			}
+			continue
+		}
+		if err := bot.store.ForgetUnmapped(botUser.Name); err != nil {
+			telegramLogger.Errorf("Can't forget unmapped user %s: %s", botUser.Name, err.Error())
+		}
+		if !bot.permitted(botUser, n) {
+			telegramLogger.Warnf("Issue #%d. User %s has no access to the event, skip", issueID, botUser.Name)
+			continue
+		}
+		bot.notifyUser(botUser, n, now)
+	}
+}
+
+// notifyUser - deliver, postpone or skip notification according to user's preferences
+func (bot *Bot) notifyUser(botUser issue.BotUser, n *notification, now time.Time) {
+	issueID := n.Attributes.ID
+	user, _ := bot.store.GetUser(botUser.TelegramID)
+	if !user.Receives() {
+		telegramLogger.Infof("Issue #%d. User %s is %s", issueID, botUser.Name, user.Status)
+		return
+	}
+	if bot.store.IsMuted(botUser.TelegramID, n.issueKey(), now) && !(user.Settings.MentionsOverrideMute && botUser.IsMentioned()) {
+		telegramLogger.Infof("Issue #%d. Issue is muted by user %s", issueID, botUser.Name)
+		return
+	}
+	if !bot.wants(botUser, n) {
+		telegramLogger.Infof("Issue #%d. Event %s is disabled in settings of user %s", issueID, n.Kind, botUser.Name)
+		return
+	}
+	if !passesFilters(user.Filters, n) {
+		telegramLogger.Infof("Issue #%d. Event is filtered out by user %s", issueID, botUser.Name)
+		return
+	}
+	if user.Digest.Enabled() && !bot.isUrgent(n) {
+		if err := bot.store.AddDigestEvents(botUser.TelegramID, n.summaryEvent(now)); err != nil {
+			telegramLogger.Errorf("Issue #%d. Can't add event to digest of user %s: %s", issueID, botUser.Name, err.Error())
+		} else {
+			telegramLogger.Infof("Issue #%d. Event is added to digest of user %s", issueID, botUser.Name)
+		}
+		return
+	}
+	if user.Quiet.Active(now) && !bot.isUrgent(n) {
+		if err := bot.store.HoldEvents(botUser.TelegramID, n.summaryEvent(now)); err != nil {
+			telegramLogger.Errorf("Issue #%d. Can't hold event for user %s: %s", issueID, botUser.Name, err.Error())
+		} else {
+			telegramLogger.Infof("Issue #%d. Event is held till quiet hours of user %s are over", issueID, botUser.Name)
+		}
+		return
+	}
+	if err := bot.deliver(int64(botUser.TelegramID), n); err != nil {
+		telegramLogger.Errorf("Issue #%d. Error when sending notification to user %s: %s", issueID, botUser.Name, err)
+	} else {
+		telegramLogger.Infof("Issue #%d. Notifaction was sent to user %s", issueID, botUser.Name)
+	}
+}
+
+// render - convert issue event into notification
+func (bot *Bot) render(event issue.Issue) (*notification, error) {
+	n := &notification{Instance: event.Instance}
+	gitlabClient := bot.client(event.Instance)
+	if event.IssueBody != nil {
+		if err := event.IssueBody.ConvIDsToNames(gitlabClient); err != nil {
+			telegramLogger.Errorf("Can't get gitlab user names from IDs: %s", err.Error())
+		}
+		n.Text = event.IssueBody.BeautifyNotification()
+		n.Attributes = event.IssueBody.ObjectAttributes
+		n.Kind = n.Attributes.Action
+		n.LabelsOnly = event.IssueBody.LabelsOnlyUpdate()
+		n.Reassigned = event.IssueBody.AssigneesChanged()
+		n.ProjectPath = event.IssueBody.Project.PathWithNamespace
+		n.PrivateProject = event.IssueBody.Project.IsPrivate()
+		n.PublicProject = event.IssueBody.Project.IsPublic()
+	} else if event.IssueNote != nil {
+		if err := event.IssueNote.ConvIDsToNames(gitlabClient); err != nil {
+			telegramLogger.Errorf("Can't get gitlab user names from IDs: %s", err.Error())
+		}
+		n.Text = event.IssueNote.BeautifyNotification()
+		n.Attributes = event.IssueNote.Issue
+		n.Kind = EventComment
+		n.ProjectPath = event.IssueNote.Project.PathWithNamespace
+		n.PrivateProject = event.IssueNote.Project.IsPrivate()
+		n.PublicProject = event.IssueNote.Project.IsPublic()
+		n.InternalNote = event.IssueNote.ObjectAttributes.Internal
+		n.Comment = event.IssueNote.ObjectAttributes.Note
+		n.CommentAuthor = event.IssueNote.User.Name
+		if event.IssueNote.ObjectAttributes.Type == issue.DiscussionNoteType {
+			n.DiscussionID = event.IssueNote.ObjectAttributes.DiscussionID
+		}
+	} else {
+		return nil, errors.New("Can't determine event type, nor issue or comment")
+	}
+	if n.isConfidential() && bot.conf().RedactConfidential {
+		n.redact()
+	}
+	// Redacted notifications get no actions, their results would reveal issue details
+	if !n.Redacted {
+		n.Keyboard = bot.issueKeyboard(n.Attributes)
+	}
+	n.Priority = bot.priorityOf(n.Attributes)
+	n.Alert = bot.needsAlert(event, n)
+	return n, nil
+}
+
+// redact - strip confidential content from notification
+func (n *notification) redact() {
+	n.Text = issue.RedactedNotification(n.Attributes, n.InternalNote)
+	n.Attributes.Title = "Confidential issue"
+	n.Attributes.Description = ""
+	n.Comment = ""
+	n.CommentAuthor = ""
+	n.Redacted = true
+}
+
+// deliver - send notification to chat in chat's style
+func (bot *Bot) deliver(chatID int64, n *notification) error {
+	var err error
+	style := bot.styleFor(chatID)
+	if bot.store.TopicMode(chatID) != "" {
+		// Topics keep issues apart themselves, cards would be lost in them
+		style = StyleMessages
+	}
+	if n.Redacted {
+		// Cards show issue details
+		style = StyleMessages
+	}
+	switch style {
+	case StyleCard:
+		err = bot.deliverCard(chatID, n, false)
+	case StyleCardPing:
+		err = bot.deliverCard(chatID, n, true)
+	default:
+		err = bot.deliverMessage(chatID, n)
+	}
+	bot.checkChatGone(chatID, err)
+	bot.stats.countDelivery(err)
+	return err
+}
+
+// deliverMessage - send notification to chat as reply to the first message about the issue
+func (bot *Bot) deliverMessage(chatID int64, n *notification) error {
+	now := time.Now()
+	options := &tb.SendOptions{ParseMode: tb.ModeMarkdownV2, ReplyMarkup: n.Keyboard, DisableNotification: n.Priority == PriorityLow}
+	if n.Alert {
+		options.ReplyMarkup = bot.alertKeyboard(n)
+	}
+	threadMessageID, threaded := bot.store.GetThread(chatID, n.issueKey(), now, bot.conf().ThreadExpiry)
+	if threaded && bot.store.TopicMode(chatID) != storage.TopicsPerIssue {
+		options.ReplyTo = &tb.Message{ID: threadMessageID}
+		options.AllowWithoutReply = true
+	}
+	message, err := bot.sendNotification(chatID, n, options)
+	if err != nil {
+		return err
+	}
+	bot.rememberMessage(chatID, message.ID, n, now)
+	if n.Alert {
+		bot.raiseAlert(chatID, message.ID, n, now)
+	}
+	if err := bot.store.TrackThread(chatID, n.issueKey(), message.ID, n.Attributes.State == "closed", now, bot.conf().ThreadExpiry); err != nil {
+		telegramLogger.Errorf("Issue #%d. Can't track thread: %s", n.Attributes.ID, err.Error())
+	}
+	return nil
+}
+
+// rememberMessage - remember which issue the message is about to handle replies to it
+func (bot *Bot) rememberMessage(chatID int64, messageID int, n *notification, now time.Time) {
+	if err := bot.store.RememberMessage(chatID, messageID, storage.MessageRef{
+		Instance:     n.Instance,
+		ProjectID:    n.Attributes.ProjectID,
+		IssueIID:     n.Attributes.ID,
+		IssueURL:     n.Attributes.URL,
+		DiscussionID: n.DiscussionID,
+		SentAt:       now,
+	}); err != nil {
+		telegramLogger.Errorf("Issue #%d. Can't remember message: %s", n.Attributes.ID, err.Error())
+	}
+}
@@ -0,0 +1,185 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	storage "github.com/aberestyak/gitlab-issue-bot/internal/storage"
+	issue "github.com/aberestyak/gitlab-issue-bot/pkg/model"
+	utils "github.com/aberestyak/gitlab-issue-bot/utils"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+// Delivery priorities of notifications
+const (
+	PriorityLow      = "low"
+	PriorityNormal   = "normal"
+	PriorityCritical = "critical"
+)
+
+// typePatternPrefix - priority patterns with this prefix match issue type instead of labels
+const typePatternPrefix = "type:"
+
+// priorityOf - delivery priority by issue labels and type. Critical wins over low
+func (bot *Bot) priorityOf(attributes issue.Attibutes) string {
+	if matchesPriority(bot.conf().CriticalLabels, attributes) {
+		return PriorityCritical
+	}
+	if matchesPriority(bot.conf().LowPriorityLabels, attributes) {
+		return PriorityLow
+	}
+	return PriorityNormal
+}
+
+func matchesPriority(patterns []string, attributes issue.Attibutes) bool {
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, typePatternPrefix) {
+			if strings.EqualFold(strings.TrimPrefix(pattern, typePatternPrefix), attributes.Type) {
+				return true
+			}
+			continue
+		}
+		for _, label := range attributes.Labels {
+			if utils.MatchPath(pattern, label.Title) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// needsAlert - critical issue was opened, reopened or has just become critical
+func (bot *Bot) needsAlert(event issue.Issue, n *notification) bool {
+	if n.Priority != PriorityCritical || event.IssueBody == nil {
+		return false
+	}
+	switch n.Kind {
+	case "open", "reopen":
+		return true
+	case "update":
+		previousLabels, changed := event.IssueBody.PreviousLabels()
+		if !changed {
+			return false
+		}
+		previous := n.Attributes
+		previous.Labels = previousLabels
+		return bot.priorityOf(previous) != PriorityCritical
+	}
+	return false
+}
+
+// alertKeyboard - issue keyboard with acknowledge button
+func (bot *Bot) alertKeyboard(n *notification) *tb.ReplyMarkup {
+	markup := &tb.ReplyMarkup{}
+	if n.Keyboard != nil {
+		markup.InlineKeyboard = append(markup.InlineKeyboard, n.Keyboard.InlineKeyboard...)
+	}
+	markup.InlineKeyboard = append(markup.InlineKeyboard, []tb.InlineButton{bot.ackButton(n.Instance, n.Attributes)})
+	return markup
+}
+
+func (bot *Bot) ackButton(instance string, attributes issue.Attibutes) tb.InlineButton {
+	return callbackButton(bot.conf().CallbackSecret, "✋ Acknowledge", CallbackData{Action: ActionAck, Arg: instance}, attributes)
+}
+
+// raiseAlert - pin critical notification in group chat and re-ping it until acknowledged
+func (bot *Bot) raiseAlert(chatID int64, messageID int, n *notification, now time.Time) {
+	alert := storage.Alert{
+		ChatID:    chatID,
+		MessageID: messageID,
+		IssueKey:  n.issueKey(),
+		IssueURL:  n.Attributes.URL,
+		NextPing:  now.Add(bot.conf().RepingInterval),
+	}
+	if chatID < 0 {
+		if err := bot.telegram.Pin(&tb.Message{ID: messageID, Chat: &tb.Chat{ID: chatID}}); err != nil {
+			telegramLogger.Errorf("Issue #%d. Can't pin message in chat %d: %s", n.Attributes.ID, chatID, err.Error())
+		} else {
+			alert.Pinned = true
+		}
+	}
+	if err := bot.store.AddAlert(alert); err != nil {
+		telegramLogger.Errorf("Issue #%d. Can't save alert: %s", n.Attributes.ID, err.Error())
+	}
+}
+
+// ackFromCallback - stop re-pinging issue in chat
+func (bot *Bot) ackFromCallback(c *tb.Callback, data CallbackData) {
+	// Alerts are kept per chat, inline messages have none
+	if c.Message == nil || c.Message.Chat == nil {
+		bot.respond(c, "Can't acknowledge issue here", true)
+		return
+	}
+	alerts, err := bot.store.TakeAlerts(c.Message.Chat.ID, storage.InstanceIssueKey(data.Arg, data.ProjectID, data.IssueIID))
+	if err != nil {
+		telegramLogger.Errorf("Can't acknowledge issue #%d: %s", data.IssueIID, err.Error())
+		bot.respond(c, "Can't acknowledge issue", true)
+		return
+	}
+	bot.dropAlerts(alerts)
+	// Acknowledge button is the last row
+	if rows := c.Message.ReplyMarkup.InlineKeyboard; len(rows) > 0 {
+		bot.editMarkup(c, &tb.ReplyMarkup{InlineKeyboard: rows[:len(rows)-1]})
+	}
+	if len(alerts) > 0 {
+		text := fmt.Sprintf("✅ Issue #%d acknowledged by %s", data.IssueIID, strings.TrimSpace(c.Sender.FirstName+" "+c.Sender.LastName))
+		if _, err := bot.telegram.Send(c.Message.Chat, text, &tb.SendOptions{ReplyTo: c.Message, AllowWithoutReply: true}); err != nil {
+			telegramLogger.Errorf("Error while send message: %s", err.Error())
+		}
+	}
+	bot.respond(c, "Acknowledged", false)
+}
+
+// resolveAlerts - stop re-pinging closed issue everywhere
+func (bot *Bot) resolveAlerts(n *notification) {
+	alerts, err := bot.store.TakeAlerts(0, n.issueKey())
+	if err != nil {
+		telegramLogger.Errorf("Issue #%d. Can't resolve alerts: %s", n.Attributes.ID, err.Error())
+		return
+	}
+	bot.dropAlerts(alerts)
+}
+
+// dropAlerts - unpin messages of removed alerts
+func (bot *Bot) dropAlerts(alerts []storage.Alert) {
+	for _, alert := range alerts {
+		if !alert.Pinned {
+			continue
+		}
+		if err := bot.telegram.Unpin(&tb.Chat{ID: alert.ChatID}, alert.MessageID); err != nil {
+			telegramLogger.Errorf("Can't unpin message %d in chat %d: %s", alert.MessageID, alert.ChatID, err.Error())
+		}
+	}
+}
+
+// repingAlerts - remind about critical issues nobody acknowledged
+func (bot *Bot) repingAlerts(now time.Time) {
+	alerts, err := bot.store.DueAlerts(now, bot.conf().RepingInterval, bot.conf().RepingLimit)
+	if err != nil {
+		telegramLogger.Errorf("Can't get due alerts: %s", err.Error())
+		return
+	}
+	for _, alert := range alerts {
+		projectID, issueIID, err := storage.ParseIssueKey(alert.IssueKey)
+		if err != nil {
+			continue
+		}
+		markup := &tb.ReplyMarkup{InlineKeyboard: [][]tb.InlineButton{{
+			bot.ackButton(storage.IssueKeyInstance(alert.IssueKey), issue.Attibutes{ProjectID: projectID, ID: issueIID}),
+		}}}
+		text := fmt.Sprintf("🚨 Critical issue #%d is still not acknowledged: %s", issueIID, alert.IssueURL)
+		_, err = bot.telegram.Send(&tb.Chat{ID: alert.ChatID}, text, &tb.SendOptions{
+			ReplyTo:           &tb.Message{ID: alert.MessageID},
+			AllowWithoutReply: true,
+			ReplyMarkup:       markup,
+		})
+		if err != nil {
+			telegramLogger.Errorf("Can't re-ping issue #%d in chat %d: %s", issueIID, alert.ChatID, err.Error())
+			bot.checkChatGone(alert.ChatID, err)
+			if isChatGone(err) {
+				bot.store.TakeAlerts(alert.ChatID, alert.IssueKey)
+			}
+		}
+	}
+}
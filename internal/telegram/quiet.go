@@ -0,0 +1,173 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	storage "github.com/aberestyak/gitlab-issue-bot/internal/storage"
+	issue "github.com/aberestyak/gitlab-issue-bot/pkg/model"
+	utils "github.com/aberestyak/gitlab-issue-bot/utils"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+const quietUsage = "Usage: /quiet HH:MM-HH:MM [weekends], /quiet weekends or /quiet off"
+
+// handleTimezone - set user's time zone
+func (bot *Bot) handleTimezone(m *tb.Message) {
+	timeZone := strings.TrimSpace(m.Payload)
+	if timeZone == "" {
+		user, _ := bot.store.GetUser(m.Sender.ID)
+		bot.reply(m, fmt.Sprintf("Your time zone is %s. Change it with /timezone <zone>, e.g. /timezone Europe/Berlin", user.Quiet.Location()))
+		return
+	}
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		bot.reply(m, fmt.Sprintf("Unknown time zone %s", timeZone))
+		return
+	}
+	if _, err := bot.store.SetQuietHours(m.Sender.ID, func(quiet *storage.QuietHours) {
+		quiet.TimeZone = timeZone
+	}); err != nil {
+		telegramLogger.Errorf("Can't save time zone for user %d: %s", m.Sender.ID, err.Error())
+		bot.reply(m, "Can't save time zone, try again later")
+		return
+	}
+	bot.reply(m, fmt.Sprintf("Time zone is set to %s", timeZone))
+}
+
+// handleQuiet - show or change user's quiet hours
+func (bot *Bot) handleQuiet(m *tb.Message) {
+	args := strings.Fields(m.Payload)
+	if len(args) == 0 {
+		user, _ := bot.store.GetUser(m.Sender.ID)
+		bot.reply(m, describeQuietHours(user.Quiet)+"\n"+quietUsage)
+		return
+	}
+
+	var start, end string
+	weekends := false
+	switch {
+	case args[0] == "off":
+	case args[0] == "weekends" && len(args) == 1:
+		weekends = true
+	case len(args) <= 2:
+		window := strings.Split(args[0], "-")
+		if len(window) != 2 {
+			bot.reply(m, quietUsage)
+			return
+		}
+		for _, clock := range window {
+			if _, err := storage.ParseClock(clock); err != nil {
+				bot.reply(m, err.Error())
+				return
+			}
+		}
+		start, end = window[0], window[1]
+		if len(args) == 2 {
+			if args[1] != "weekends" {
+				bot.reply(m, quietUsage)
+				return
+			}
+			weekends = true
+		}
+	default:
+		bot.reply(m, quietUsage)
+		return
+	}
+
+	quiet, err := bot.store.SetQuietHours(m.Sender.ID, func(quiet *storage.QuietHours) {
+		quiet.Start, quiet.End, quiet.Weekends = start, end, weekends
+	})
+	if err != nil {
+		telegramLogger.Errorf("Can't save quiet hours for user %d: %s", m.Sender.ID, err.Error())
+		bot.reply(m, "Can't save quiet hours, try again later")
+		return
+	}
+	bot.reply(m, describeQuietHours(quiet))
+}
+
+func describeQuietHours(quiet storage.QuietHours) string {
+	var parts []string
+	if quiet.Start != "" {
+		parts = append(parts, fmt.Sprintf("%s-%s", quiet.Start, quiet.End))
+	}
+	if quiet.Weekends {
+		parts = append(parts, "weekends")
+	}
+	if len(parts) == 0 {
+		return "Quiet hours are off"
+	}
+	return fmt.Sprintf("Quiet hours: %s (%s). Urgent events are delivered anyway", strings.Join(parts, " and "), quiet.Location())
+}
+
+// isUrgent - urgent and critical events are delivered even during quiet hours
+func (bot *Bot) isUrgent(n *notification) bool {
+	if n.Priority == PriorityCritical {
+		return true
+	}
+	for _, pattern := range bot.conf().UrgentLabels {
+		for _, label := range n.Attributes.Labels {
+			if utils.MatchPath(pattern, label.Title) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// summaryEvent - notification reduced to summary line
+func (n *notification) summaryEvent(now time.Time) issue.SummaryEvent {
+	kind := n.Kind
+	if n.Reassigned {
+		kind = "assign"
+	}
+	return issue.SummaryEvent{
+		ProjectPath: n.ProjectPath,
+		IssueIID:    n.Attributes.ID,
+		IssueURL:    n.Attributes.URL,
+		IssueTitle:  n.Attributes.Title,
+		Kind:        kind,
+		At:          now,
+	}
+}
+
+// flushHeld - send summary of held events to users whose quiet hours are over
+func (bot *Bot) flushHeld(now time.Time) {
+	for _, telegramID := range bot.store.UsersWithHeldEvents() {
+		user, _ := bot.store.GetUser(telegramID)
+		if !user.Receives() || user.Quiet.Active(now) {
+			continue
+		}
+		bot.sendSummary(telegramID, "🌙 While you were away", bot.store.TakeHeldEvents, bot.store.HoldEvents)
+	}
+}
+
+// sendSummary - send summary of taken events, put them back if sending failed
+func (bot *Bot) sendSummary(telegramID int, title string, take func(int) ([]issue.SummaryEvent, error), putBack func(int, ...issue.SummaryEvent) error) {
+	events, err := take(telegramID)
+	if err != nil {
+		telegramLogger.Errorf("Can't take postponed events of user %d: %s", telegramID, err.Error())
+		return
+	}
+	if len(events) == 0 {
+		return
+	}
+	summary := &issue.Summary{Title: title, Events: events}
+	parts := summary.Split(issue.MessageLimit)
+	for index, part := range parts {
+		if _, err := bot.telegram.Send(&tb.Chat{ID: int64(telegramID)}, part.BeautifyNotification(), &tb.SendOptions{ParseMode: tb.ModeMarkdownV2}); err != nil {
+			telegramLogger.Errorf("Can't send summary to user %d: %s", telegramID, err.Error())
+			bot.checkChatGone(int64(telegramID), err)
+			// Sent parts are not repeated
+			var unsent []issue.SummaryEvent
+			for _, part := range parts[index:] {
+				unsent = append(unsent, part.Events...)
+			}
+			if err := putBack(telegramID, unsent...); err != nil {
+				telegramLogger.Errorf("Can't put back postponed events of user %d: %s", telegramID, err.Error())
+			}
+			return
+		}
+	}
+	telegramLogger.Infof("Summary of %d events was sent to user %d in %d messages", len(events), telegramID, len(parts))
+}
@@ -0,0 +1,119 @@
+package telegram
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	gitlabUserAPI "github.com/aberestyak/gitlab-issue-bot/internal/gitlabAPI"
+	storage "github.com/aberestyak/gitlab-issue-bot/internal/storage"
+	issue "github.com/aberestyak/gitlab-issue-bot/pkg/model"
+	"github.com/xanzy/go-gitlab"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+// handleText - handle plain text messages
+func (bot *Bot) handleText(m *tb.Message) {
+	if m.ReplyTo != nil && m.ReplyTo.Sender != nil && m.ReplyTo.Sender.ID == bot.telegram.Me.ID {
+		bot.handleReply(m)
+		return
+	}
+	if dialog, found := bot.dialogs.get(m.Sender.ID); found && m.Private() {
+		bot.continueConversation(m, dialog, m.Text)
+	}
+}
+
+// handleReply - post reply to notification as comment to the issue
+func (bot *Bot) handleReply(m *tb.Message) {
+	ref, found := bot.store.GetMessage(m.Chat.ID, m.ReplyTo.ID)
+	if !found {
+		bot.replyTo(m, "I don't remember which issue this message is about")
+		return
+	}
+	gitlabClient := bot.client(ref.Instance)
+	user, linked := bot.store.GetUser(m.Sender.ID)
+	user = user.On(ref.Instance)
+	if !linked || user.GitlabID == 0 {
+		bot.replyTo(m, "Link your GitLab account first: /link <gitlab username>")
+		return
+	}
+	permitted, err := bot.canComment(gitlabClient, user, ref)
+	if err != nil {
+		telegramLogger.Errorf("Can't check permissions of %s to comment issue #%d of project %d: %s", user.GitlabUsername, ref.IssueIID, ref.ProjectID, err.Error())
+		bot.replyTo(m, "Can't check your permissions in GitLab, try again later")
+		return
+	}
+	if !permitted {
+		telegramLogger.Warnf("Gitlab user %s isn't permitted to comment issue #%d of project %d", user.GitlabUsername, ref.IssueIID, ref.ProjectID)
+		bot.replyTo(m, "You are not permitted to comment this issue")
+		return
+	}
+
+	body := bot.commentBody(user, m.Text)
+	var note *gitlab.Note
+	if ref.DiscussionID != "" {
+		note, _, err = gitlabClient.Discussions.AddIssueDiscussionNote(ref.ProjectID, ref.IssueIID, ref.DiscussionID,
+			&gitlab.AddIssueDiscussionNoteOptions{Body: &body}, bot.actAs(user)...)
+	} else {
+		note, _, err = gitlabClient.Notes.CreateIssueNote(ref.ProjectID, ref.IssueIID,
+			&gitlab.CreateIssueNoteOptions{Body: &body}, bot.actAs(user)...)
+	}
+	if err != nil {
+		telegramLogger.Errorf("Can't comment issue #%d of project %d: %s", ref.IssueIID, ref.ProjectID, err.Error())
+		bot.replyTo(m, "Can't post comment to GitLab")
+		return
+	}
+	telegramLogger.Infof("Issue #%d of project %d commented by %s", ref.IssueIID, ref.ProjectID, user.GitlabUsername)
+	bot.replyTo(m, fmt.Sprintf("Comment posted: %s#note_%d", ref.IssueURL, note.ID))
+}
+
+// discussionNotes - thread notes with fields unknown to go-gitlab
+type discussionNotes struct {
+	Notes []struct {
+		Internal     bool `json:"internal"`
+		Confidential bool `json:"confidential"`
+	} `json:"notes"`
+}
+
+// canComment - check member may read the issue, or the internal thread the reply goes to.
+// Without sudo comments are posted by bot token, so GitLab doesn't check it
+func (bot *Bot) canComment(gitlabClient *gitlab.Client, user storage.User, ref storage.MessageRef) (bool, error) {
+	accessLevel, err := gitlabUserAPI.GetAccessLevel(ref.ProjectID, user.GitlabID, gitlabClient)
+	if err != nil {
+		return false, err
+	}
+	gitlabIssue, _, err := gitlabClient.Issues.GetIssue(ref.ProjectID, ref.IssueIID)
+	if err != nil {
+		return false, err
+	}
+	internalThread := false
+	if ref.DiscussionID != "" {
+		request, err := gitlabClient.NewRequest(http.MethodGet, fmt.Sprintf("projects/%d/issues/%d/discussions/%s", ref.ProjectID, ref.IssueIID, ref.DiscussionID), nil, nil)
+		if err != nil {
+			return false, err
+		}
+		discussion := &discussionNotes{}
+		if _, err := gitlabClient.Do(request, discussion); err != nil {
+			return false, err
+		}
+		if len(discussion.Notes) > 0 {
+			internalThread = discussion.Notes[0].Internal || discussion.Notes[0].Confidential
+		}
+	}
+	return canRead(accessLevel, false, user.GitlabID, issue.AttributesFromGitlab(gitlabIssue), internalThread), nil
+}
+
+// commentBody - mark comment posted by bot token as written on behalf of linked user
+func (bot *Bot) commentBody(user storage.User, text string) string {
+	if bot.conf().GitlabSudo {
+		return text
+	}
+	return fmt.Sprintf("On behalf of `%s` via Telegram:\n\n%s", user.GitlabUsername, strings.TrimSpace(text))
+}
+
+// replyTo - send plain text reply quoting the message
+func (bot *Bot) replyTo(m *tb.Message, text string) {
+	if _, err := bot.telegram.Reply(m, text); err != nil {
+		telegramLogger.Errorf("Error while send message: %s", err.Error())
+	}
+}
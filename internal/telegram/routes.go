@@ -0,0 +1,149 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+
+	storage "github.com/aberestyak/gitlab-issue-bot/internal/storage"
+	utils "github.com/aberestyak/gitlab-issue-bot/utils"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+const routeUsage = "Usage inside group chat: /route add|remove <project pattern>, e.g. /route add payments/*. /route to list routes of this chat"
+
+// handleRoute - manage routes of group chat. Only bot administrators may change them
+func (bot *Bot) handleRoute(m *tb.Message) {
+	if m.Private() {
+		bot.reply(m, routeUsage)
+		return
+	}
+	bot.route(m, m.Payload)
+}
+
+// handleChannelPost - channels don't get commands, handle /route posted to channel.
+// Channel posts have no sender, so channel routes can only be listed
+func (bot *Bot) handleChannelPost(m *tb.Message) {
+	command := strings.Fields(m.Text)
+	if len(command) == 0 || strings.Split(command[0], "@")[0] != "/route" {
+		return
+	}
+	bot.route(m, strings.TrimSpace(strings.TrimPrefix(m.Text, command[0])))
+}
+
+// route - add, remove or list routes of chat the message is sent to
+func (bot *Bot) route(m *tb.Message, payload string) {
+	args := strings.Fields(payload)
+	if len(args) == 0 {
+		bot.listRoutes(m)
+		return
+	}
+	if len(args) != 2 || (args[0] != "add" && args[0] != "remove") {
+		bot.reply(m, routeUsage)
+		return
+	}
+	if m.Sender == nil || !bot.isAdminID(m.Sender.ID) {
+		bot.reply(m, "Only bot administrators can change routes. Channels are routed with ROUTES setting")
+		return
+	}
+	route := storage.Route{Pattern: args[1], ChatID: m.Chat.ID}
+	var err error
+	if args[0] == "add" {
+		err = bot.store.AddRoute(route)
+	} else {
+		err = bot.store.RemoveRoute(route)
+	}
+	if err != nil {
+		bot.reply(m, fmt.Sprintf("Can't %s route: %s", args[0], err.Error()))
+		return
+	}
+	telegramLogger.Infof("Route %s of chat %d: %s", route.Pattern, route.ChatID, args[0])
+	if args[0] == "add" {
+		bot.reply(m, fmt.Sprintf("Events of public projects matching %s will be sent to this chat. Private and internal projects are routed only with ROUTES setting", route.Pattern))
+	} else {
+		bot.reply(m, fmt.Sprintf("Events of %s won't be sent to this chat anymore", route.Pattern))
+	}
+}
+
+// listRoutes - list patterns routed to chat, including configured ones
+func (bot *Bot) listRoutes(m *tb.Message) {
+	var patterns []string
+	for _, route := range bot.conf().Routes {
+		for _, chatID := range route.ChatIDs {
+			if chatID == m.Chat.ID {
+				patterns = append(patterns, route.Pattern+" (config)")
+			}
+		}
+	}
+	for _, route := range bot.store.Routes() {
+		if route.ChatID == m.Chat.ID {
+			patterns = append(patterns, route.Pattern+" (public projects only)")
+		}
+	}
+	if len(patterns) == 0 {
+		bot.reply(m, "No events are routed to this chat. "+routeUsage)
+		return
+	}
+	bot.reply(m, "Events routed to this chat:\n"+strings.Join(patterns, "\n"))
+}
+
+// isChatAdmin - check user is administrator of group chat
+func (bot *Bot) isChatAdmin(chat *tb.Chat, user *tb.User) bool {
+	if user == nil {
+		return false
+	}
+	admins, err := bot.telegram.AdminsOf(chat)
+	if err != nil {
+		telegramLogger.Errorf("Can't get administrators of chat %d: %s", chat.ID, err.Error())
+		return false
+	}
+	for _, admin := range admins {
+		if admin.User != nil && admin.User.ID == user.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// routedChats - group chats and channels receiving events of project. Routes added
+// from Telegram are skipped if configuredOnly is set
+func (bot *Bot) routedChats(projectPath string, configuredOnly bool) []int64 {
+	var chatIDs []int64
+	seen := map[int64]bool{}
+	add := func(pattern string, chatID int64) {
+		if !seen[chatID] && utils.MatchPath(pattern, projectPath) {
+			seen[chatID] = true
+			chatIDs = append(chatIDs, chatID)
+		}
+	}
+	for _, route := range bot.conf().Routes {
+		for _, chatID := range route.ChatIDs {
+			add(route.Pattern, chatID)
+		}
+	}
+	if configuredOnly {
+		return chatIDs
+	}
+	for _, route := range bot.store.Routes() {
+		add(route.Pattern, route.ChatID)
+	}
+	return chatIDs
+}
+
+// notifyChats - deliver notification to routed group chats and channels
+func (bot *Bot) notifyChats(n *notification) {
+	issueID := n.Attributes.ID
+	// Events of non-public projects go only to chats trusted by deployment config
+	chatIDs := bot.routedChats(n.ProjectPath, !n.PublicProject)
+	if n.isConfidential() && len(chatIDs) > 0 {
+		// Access of chat members can't be checked
+		telegramLogger.Infof("Issue #%d. Confidential event is not sent to routed chats", issueID)
+		return
+	}
+	for _, chatID := range chatIDs {
+		if err := bot.deliver(chatID, n); err != nil {
+			telegramLogger.Errorf("Issue #%d. Error when sending notification to chat %d: %s", issueID, chatID, err)
+		} else {
+			telegramLogger.Infof("Issue #%d. Notifaction was sent to chat %d", issueID, chatID)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package telegram
+
+import (
+	storage "github.com/aberestyak/gitlab-issue-bot/internal/storage"
+	issue "github.com/aberestyak/gitlab-issue-bot/pkg/model"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+// EventComment - kind of comment events. Other kinds are issue actions
+const EventComment = "comment"
+
+// eventKinds - event kinds user may switch off
+var eventKinds = []struct {
+	Kind  string
+	Title string
+}{
+	{"open", "New issues"},
+	{"update", "Updates"},
+	{"close", "Closing"},
+	{"reopen", "Reopening"},
+	{EventComment, "Comments"},
+}
+
+// Settings menu buttons besides event kinds
+const (
+	settingCommentsOnlyAssigned = "ca"
+	settingIgnoreLabelsOnly     = "il"
+	settingMentionsOverrideMute = "mm"
+	settingDone                 = "ok"
+)
+
+const settingsTitle = "⚙️ Notification settings"
+
+// handleSettings - show settings menu
+func (bot *Bot) handleSettings(m *tb.Message) {
+	user, _ := bot.store.GetUser(m.Sender.ID)
+	if _, err := bot.telegram.Send(m.Chat, settingsTitle, bot.settingsKeyboard(user.Settings)); err != nil {
+		telegramLogger.Errorf("Error while send message: %s", err.Error())
+	}
+}
+
+// settingsKeyboard - build settings menu with current values
+func (bot *Bot) settingsKeyboard(settings storage.Settings) *tb.ReplyMarkup {
+	markup := &tb.ReplyMarkup{}
+	for _, eventKind := range eventKinds {
+		markup.InlineKeyboard = append(markup.InlineKeyboard, []tb.InlineButton{
+			bot.settingButton(checkbox(settings.EventEnabled(eventKind.Kind))+eventKind.Title, eventKind.Kind),
+		})
+	}
+	markup.InlineKeyboard = append(markup.InlineKeyboard,
+		[]tb.InlineButton{bot.settingButton(checkbox(settings.CommentsOnlyAssigned)+"Comments only on issues assigned to me", settingCommentsOnlyAssigned)},
+		[]tb.InlineButton{bot.settingButton(checkbox(settings.IgnoreLabelOnlyUpdates)+"Ignore label-only updates", settingIgnoreLabelsOnly)},
+		[]tb.InlineButton{bot.settingButton(checkbox(settings.MentionsOverrideMute)+"Notify about mentions in muted issues", settingMentionsOverrideMute)},
+		[]tb.InlineButton{bot.settingButton("Done", settingDone)},
+	)
+	return markup
+}
+
+func (bot *Bot) settingButton(text string, setting string) tb.InlineButton {
+	return callbackButton(bot.conf().CallbackSecret, text, CallbackData{Action: ActionSetting, Arg: setting}, issue.Attibutes{})
+}
+
+func checkbox(checked bool) string {
+	if checked {
+		return "✅ "
+	}
+	return "⬜️ "
+}
+
+// toggleSetting - handle settings menu button
+func (bot *Bot) toggleSetting(c *tb.Callback, data CallbackData) {
+	if data.Arg == settingDone {
+		if _, err := bot.telegram.Edit(c.Message, settingsTitle+": saved"); err != nil && !isNotModified(err) {
+			telegramLogger.Errorf("Can't update message: %s", err.Error())
+		}
+		bot.respond(c, "", false)
+		return
+	}
+	settings, err := bot.store.UpdateSettings(c.Sender.ID, func(settings *storage.Settings) {
+		switch data.Arg {
+		case settingCommentsOnlyAssigned:
+			settings.CommentsOnlyAssigned = !settings.CommentsOnlyAssigned
+		case settingIgnoreLabelsOnly:
+			settings.IgnoreLabelOnlyUpdates = !settings.IgnoreLabelOnlyUpdates
+		case settingMentionsOverrideMute:
+			settings.MentionsOverrideMute = !settings.MentionsOverrideMute
+		default:
+			settings.ToggleEvent(data.Arg)
+		}
+	})
+	if err != nil {
+		telegramLogger.Errorf("Can't save settings for user %d: %s", c.Sender.ID, err.Error())
+		bot.respond(c, "Can't save settings", true)
+		return
+	}
+	bot.editMarkup(c, bot.settingsKeyboard(settings))
+	bot.respond(c, "", false)
+}
+
+// wants - check recipient's settings allow the notification
+func (bot *Bot) wants(botUser issue.BotUser, n *notification) bool {
+	user, _ := bot.store.GetUser(botUser.TelegramID)
+	settings := user.Settings
+	if !settings.EventEnabled(n.Kind) {
+		return false
+	}
+	if n.Kind == EventComment && settings.CommentsOnlyAssigned && !botUser.IsMentioned() && !isAssigned(n.Attributes, botUser.GitlabID) {
+		return false
+	}
+	if n.LabelsOnly && settings.IgnoreLabelOnlyUpdates {
+		return false
+	}
+	return true
+}
+
+func isAssigned(attributes issue.Attibutes, gitlabUserID int) bool {
+	for _, assignee := range attributes.Assignee {
+		if assignee == gitlabUserID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package telegram
+
+import (
+	"time"
+
+	storage "github.com/aberestyak/gitlab-issue-bot/internal/storage"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+// handleStart - subscribe user or resume subscription
+func (bot *Bot) handleStart(m *tb.Message) {
+	if err := bot.store.SetStatus(m.Sender.ID, storage.StatusActive, time.Now()); err != nil {
+		telegramLogger.Errorf("Can't subscribe user %d: %s", m.Sender.ID, err.Error())
+		bot.reply(m, "Can't subscribe you, try again later")
+		return
+	}
+	telegramLogger.Infof("User %s with ID %d has joined", m.Chat.Username, m.Chat.ID)
+	bot.reply(m, "You are now subscribed for issues updates! Send /stop to unsubscribe")
+	if m.Payload == "link" {
+		bot.reply(m, "Link your GitLab account to search issues: /link <gitlab username>")
+	}
+}
+
+// handleStop - unsubscribe user
+func (bot *Bot) handleStop(m *tb.Message) {
+	if err := bot.store.SetStatus(m.Sender.ID, storage.StatusStopped, time.Now()); err != nil {
+		telegramLogger.Errorf("Can't unsubscribe user %d: %s", m.Sender.ID, err.Error())
+		bot.reply(m, "Can't unsubscribe you, try again later")
+		return
+	}
+	telegramLogger.Infof("User %s with ID %d has unsubscribed", m.Chat.Username, m.Chat.ID)
+	bot.reply(m, "You are unsubscribed from issues updates. Send /start to subscribe again")
+}
+
+// handleMyChatMember - deactivate user who blocked the bot
+func (bot *Bot) handleMyChatMember(update *tb.ChatMemberUpdated) {
+	if update.Chat.Type != tb.ChatPrivate || update.NewChatMember == nil || update.NewChatMember.Role != tb.Kicked {
+		return
+	}
+	telegramLogger.Infof("User %d has blocked the bot", update.Chat.ID)
+	if err := bot.store.SetStatus(int(update.Chat.ID), storage.StatusBlocked, time.Now()); err != nil {
+		telegramLogger.Errorf("Can't deactivate user %d: %s", update.Chat.ID, err.Error())
+	}
+}
+
+// checkChatGone - deactivate user if telegram says messages can't be delivered to him anymore
+func (bot *Bot) checkChatGone(chatID int64, err error) {
+	if err == nil || !isChatGone(err) || chatID <= 0 {
+		return
+	}
+	telegramLogger.Warnf("Chat %d is unavailable, deactivating: %s", chatID, err.Error())
+	if err := bot.store.SetStatus(int(chatID), storage.StatusBlocked, time.Now()); err != nil {
+		telegramLogger.Errorf("Can't deactivate user %d: %s", chatID, err.Error())
+	}
+}
+
+// isChatGone - errors meaning bot can't write to the chat until user starts it again
+func isChatGone(err error) bool {
+	switch err {
+	case tb.ErrBlockedByUser, tb.ErrUserIsDeactivated, tb.ErrNotStartedByUser, tb.ErrChatNotFound,
+		tb.ErrBotKickedFromGroup, tb.ErrBotKickedFromSuperGroup,
+		// telebot reports "bot was kicked from the group chat" as ErrKickingChatOwner
+		tb.ErrKickingChatOwner:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package telegram
+
+import (
+	"fmt"
+
+	config "github.com/aberestyak/gitlab-issue-bot/internal/config"
+	gitlabUserAPI "github.com/aberestyak/gitlab-issue-bot/internal/gitlabAPI"
+	issue "github.com/aberestyak/gitlab-issue-bot/pkg/model"
+	"github.com/xanzy/go-gitlab"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+// handleSystemHook - onboard new users, greet new team members and unlink removed users
+func (bot *Bot) handleSystemHook(instance string, systemHook *issue.SystemHookSpec) {
+	switch {
+	case systemHook.EventName == issue.SystemUserCreate:
+		bot.onboardUser(instance, systemHook)
+	case systemHook.EventName == issue.SystemUserAddToTeam:
+		// Membership of non-public projects goes only to chats trusted by deployment config
+		public := systemHook.ProjectVisibility == string(gitlab.PublicVisibility)
+		bot.greetMember(bot.routedChats(systemHook.ProjectPath, !public), systemHook, systemHook.ProjectPath)
+	case systemHook.EventName == issue.SystemUserAddToGroup:
+		// Group routes are patterns of its projects like group/*
+		public := bot.publicGroup(instance, systemHook.GroupPath)
+		bot.greetMember(bot.routedChats(systemHook.GroupPath+"/", !public), systemHook, systemHook.GroupPath)
+	case systemHook.Blocked():
+		telegramIDs, err := bot.store.UnlinkGitlabUser(instance, systemHook.UserID)
+		if err != nil {
+			telegramLogger.Errorf("Can't unlink gitlab user %s: %s", systemHook.Login(), err.Error())
+			return
+		}
+		for _, telegramID := range telegramIDs {
+			telegramLogger.Infof("Telegram user %d unlinked from removed gitlab user %s", telegramID, systemHook.Login())
+		}
+	default:
+		telegramLogger.Debugf("System hook event %s is ignored", systemHook.EventName)
+	}
+}
+
+// onboardUser - send linking instructions to new user, or to onboarding chats if his Telegram is unknown yet
+func (bot *Bot) onboardUser(instance string, systemHook *issue.SystemHookSpec) {
+	linkCommand := "/link " + systemHook.Login()
+	if instance != "" && instance != config.DefaultInstance {
+		linkCommand += " " + instance
+	}
+	telegramID, err := gitlabUserAPI.GetTgIDByGitlabID(systemHook.UserID, bot.client(instance))
+	if err == nil && telegramID != 0 {
+		text := fmt.Sprintf("Welcome to GitLab, %s! Send %s to get notifications about your issues here", systemHook.DisplayName(), linkCommand)
+		if _, err := bot.telegram.Send(&tb.Chat{ID: int64(telegramID)}, text); err == nil {
+			telegramLogger.Infof("Onboarding instructions sent to gitlab user %s", systemHook.Login())
+			return
+		}
+	}
+	text := fmt.Sprintf("👋 New GitLab user %s (%s). To get notifications in Telegram add \"Telegram_ID: <your telegram ID>\" to GitLab profile BIO, start this bot and send %s",
+		systemHook.DisplayName(), systemHook.Login(), linkCommand)
+	for _, chatID := range bot.conf().OnboardingChats {
+		if _, err := bot.telegram.Send(&tb.Chat{ID: chatID}, text); err != nil {
+			telegramLogger.Errorf("Can't send onboarding message to chat %d: %s", chatID, err.Error())
+		}
+	}
+}
+
+// publicGroup - check group is public. Group membership events don't tell visibility
+func (bot *Bot) publicGroup(instance string, groupPath string) bool {
+	group, _, err := bot.client(instance).Groups.GetGroup(groupPath)
+	if err != nil {
+		telegramLogger.Errorf("Can't get visibility of group %s: %s", groupPath, err.Error())
+		return false
+	}
+	return group.Visibility == gitlab.PublicVisibility
+}
+
+// greetMember - greet new project or group member in team chats
+func (bot *Bot) greetMember(chatIDs []int64, systemHook *issue.SystemHookSpec, path string) {
+	access := systemHook.AccessLevel
+	if access == "" {
+		access = systemHook.GroupAccess
+	}
+	text := fmt.Sprintf("👋 Welcome %s to %s", systemHook.DisplayName(), path)
+	if access != "" {
+		text += fmt.Sprintf(" as %s", access)
+	}
+	for _, chatID := range chatIDs {
+		if _, err := bot.telegram.Send(&tb.Chat{ID: chatID}, text); err != nil {
+			telegramLogger.Errorf("Can't greet %s in chat %d: %s", systemHook.Login(), chatID, err.Error())
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	config "github.com/aberestyak/gitlab-issue-bot/internal/config"
+	gitlabUserAPI "github.com/aberestyak/gitlab-issue-bot/internal/gitlabAPI"
+	storage "github.com/aberestyak/gitlab-issue-bot/internal/storage"
+	issue "github.com/aberestyak/gitlab-issue-bot/pkg/model"
+	log "github.com/sirupsen/logrus"
+	"github.com/xanzy/go-gitlab"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+var telegramLogger = log.WithFields(log.Fields{
+	"component": "Telegram",
+})
+
+// Bot - telegram bot with access to gitlab and persistent state
+type Bot struct {
+	telegram *tb.Bot
+	gitlab   *gitlab.Client
+	gitlabs  map[string]*gitlab.Client
+	store    *storage.Store
+	config   config.BotConfig
+	// configLock - guards config replaced by /reload
+	configLock sync.RWMutex
+	stats      *deliveryStats
+	// Accessed only by scheduler goroutine
+	lastTodoPoll time.Time
+	dialogs      *conversations
+	inline       *inlineCache
+}
+
+// NewBot - create telegram bot and register handlers
+func NewBot(botConfig config.BotConfig, gitlabClients map[string]*gitlab.Client, store *storage.Store) (*Bot, error) {
+	telegramBot, err := tb.NewBot(tb.Settings{
+		Token:  botConfig.TelegramToken,
+		Poller: &tb.LongPoller{Timeout: 5 * time.Second},
+	})
+	if err != nil {
+		return nil, err
+	}
+	bot := &Bot{
+		telegram: telegramBot,
+		gitlab:   gitlabClients[config.DefaultInstance],
+		gitlabs:  gitlabClients,
+		store:    store,
+		config:   botConfig,
+		dialogs:  &conversations{byUser: map[int]conversation{}},
+		inline:   &inlineCache{entries: map[string]inlineCacheEntry{}},
+		stats:    &deliveryStats{Since: time.Now()},
+	}
+	telegramBot.Handle("/start", bot.handleStart)
+	telegramBot.Handle("/stop", bot.handleStop)
+	telegramBot.Handle(tb.OnMyChatMember, bot.handleMyChatMember)
+	telegramBot.Handle("/link", bot.handleLink)
+	telegramBot.Handle("/style", bot.handleStyle)
+	telegramBot.Handle("/settings", bot.handleSettings)
+	telegramBot.Handle("/filter", bot.handleFilter)
+	telegramBot.Handle("/unfilter", bot.handleUnfilter)
+	telegramBot.Handle("/filters", bot.handleFilters)
+	telegramBot.Handle("/timezone", bot.handleTimezone)
+	telegramBot.Handle("/quiet", bot.handleQuiet)
+	telegramBot.Handle("/digest", bot.handleDigest)
+	telegramBot.Handle("/mute", bot.handleMute)
+	telegramBot.Handle("/snooze", bot.handleSnooze)
+	telegramBot.Handle("/muted", bot.handleMuted)
+	telegramBot.Handle("/my", bot.handleMy)
+	telegramBot.Handle("/todos", bot.handleTodos)
+	telegramBot.Handle("/new", bot.handleNew)
+	telegramBot.Handle("/cancel", bot.handleCancel)
+	telegramBot.Handle("/skip", bot.handleSkip)
+	telegramBot.Handle("/route", bot.handleRoute)
+	telegramBot.Handle("/topics", bot.handleTopics)
+	telegramBot.Handle("/stats", bot.handleStats)
+	telegramBot.Handle("/users", bot.handleUsers)
+	telegramBot.Handle("/broadcast", bot.handleBroadcast)
+	telegramBot.Handle("/unmapped", bot.handleUnmapped)
+	telegramBot.Handle("/reload", bot.handleReload)
+	telegramBot.Handle(tb.OnChannelPost, bot.handleChannelPost)
+	telegramBot.Handle(tb.OnQuery, bot.handleQuery)
+	telegramBot.Handle(tb.OnCallback, bot.handleCallback)
+	telegramBot.Handle(tb.OnText, bot.handleText)
+	return bot, nil
+}
+
+// Start - start polling telegram updates. Blocks until bot is stopped
+func (bot *Bot) Start() {
+	go bot.runScheduler()
+	bot.telegram.Start()
+}
+
+// runScheduler - periodically send postponed notifications and poll To-Dos
+func (bot *Bot) runScheduler() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		bot.flushHeld(now)
+		bot.flushDigests(now)
+		bot.expireConversations(now)
+		bot.repingAlerts(now)
+		if bot.conf().GitlabSudo {
+			bot.pollTodosIfDue(now)
+		}
+	}
+}
+
+// conf - current configuration
+func (bot *Bot) conf() config.BotConfig {
+	bot.configLock.RLock()
+	defer bot.configLock.RUnlock()
+	return bot.config
+}
+
+// client - gitlab client of instance, default one if instance is unknown
+func (bot *Bot) client(instance string) *gitlab.Client {
+	if gitlabClient, found := bot.gitlabs[instance]; found {
+		return gitlabClient
+	}
+	return bot.gitlab
+}
+
+// handleLink - link telegram user with gitlab account which has his Telegram_ID in BIO
+func (bot *Bot) handleLink(m *tb.Message) {
+	args := strings.Fields(m.Payload)
+	if len(args) == 0 || len(args) > 2 {
+		bot.reply(m, "Usage: /link <gitlab username> [instance]")
+		return
+	}
+	username := strings.TrimPrefix(args[0], "@")
+	instance := config.DefaultInstance
+	if len(args) == 2 {
+		instance = args[1]
+		if _, found := bot.gitlabs[instance]; !found {
+			bot.reply(m, fmt.Sprintf("Unknown GitLab instance %s", instance))
+			return
+		}
+	}
+	gitlabClient := bot.client(instance)
+	gitlabUser, err := gitlabUserAPI.GetUserByUsername(username, gitlabClient)
+	if err != nil {
+		bot.reply(m, "Can't get user from GitLab, try again later")
+		return
+	}
+	if gitlabUser == nil {
+		bot.reply(m, fmt.Sprintf("GitLab user %s not found", username))
+		return
+	}
+	telegramID, err := gitlabUserAPI.GetTgIDByGitlabID(gitlabUser.ID, gitlabClient)
+	if err != nil || telegramID != m.Sender.ID {
+		bot.reply(m, fmt.Sprintf("Add \"Telegram_ID: %d\" to your GitLab profile BIO and try again", m.Sender.ID))
+		return
+	}
+	if err := bot.store.LinkUser(m.Sender.ID, instance, gitlabUser.ID, gitlabUser.Username); err != nil {
+		telegramLogger.Errorf("Can't link user %s: %s", gitlabUser.Username, err.Error())
+		bot.reply(m, "Can't save link, try again later")
+		return
+	}
+	telegramLogger.Infof("Telegram user %d linked with gitlab user %s of instance %s", m.Sender.ID, gitlabUser.Username, instance)
+	bot.reply(m, fmt.Sprintf("Your Telegram account is linked with GitLab user %s", gitlabUser.Username))
+}
+
+// reply - send plain text reply to the message chat
+func (bot *Bot) reply(m *tb.Message, text string) {
+	if _, err := bot.telegram.Send(m.Chat, text); err != nil {
+		telegramLogger.Errorf("Error while send message: %s", err.Error())
+	}
+}
+
+// issueKeyboard - inline keyboard for issue notification
+func (bot *Bot) issueKeyboard(attributes issue.Attibutes) *tb.ReplyMarkup {
+	return IssueKeyboard(bot.conf().CallbackSecret, bot.conf().LabelShortlist, attributes)
+}
+
+// actAs - request options to perform gitlab request as linked user if sudo is allowed
+func (bot *Bot) actAs(user storage.User) []gitlab.RequestOptionFunc {
+	if bot.conf().GitlabSudo && user.GitlabID != 0 {
+		return []gitlab.RequestOptionFunc{gitlab.WithSudo(user.GitlabID)}
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package telegram
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	storage "github.com/aberestyak/gitlab-issue-bot/internal/storage"
+	issue "github.com/aberestyak/gitlab-issue-bot/pkg/model"
+	utils "github.com/aberestyak/gitlab-issue-bot/utils"
+	"github.com/xanzy/go-gitlab"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	todosPerMessage = 10
+	todoPushTitle   = "📝 New To-Do"
+	todosUsage      = "Usage: /todos to list pending To-Dos, /todos push on|off to get new To-Dos as messages"
+)
+
+// handleTodos - list pending To-Dos or switch their push notifications
+func (bot *Bot) handleTodos(m *tb.Message) {
+	if !m.Private() {
+		bot.reply(m, "To-Dos are available only in private chat with bot")
+		return
+	}
+	user, linked := bot.store.GetUser(m.Sender.ID)
+	if !linked || user.GitlabID == 0 {
+		bot.reply(m, "Link your GitLab account first: /link <gitlab username>")
+		return
+	}
+	if !bot.conf().GitlabSudo {
+		bot.reply(m, "To-Dos are available only when bot acts on behalf of users (GITLAB_SUDO)")
+		return
+	}
+
+	args := strings.Fields(m.Payload)
+	switch {
+	case len(args) == 0:
+		text, markup, err := bot.todosMessage(user)
+		if err != nil {
+			telegramLogger.Errorf("Can't list To-Dos of user %s: %s", user.GitlabUsername, err.Error())
+			bot.reply(m, "Can't get your To-Dos from GitLab, try again later")
+			return
+		}
+		if _, err := bot.telegram.Send(m.Chat, text, &tb.SendOptions{ParseMode: tb.ModeMarkdownV2, ReplyMarkup: markup, DisableWebPagePreview: true}); err != nil {
+			telegramLogger.Errorf("Error while send message: %s", err.Error())
+		}
+	case len(args) == 2 && args[0] == "push" && (args[1] == "on" || args[1] == "off"):
+		bot.switchTodoPush(m, user, args[1] == "on")
+	default:
+		bot.reply(m, todosUsage)
+	}
+}
+
+// switchTodoPush - enable or disable messages about new To-Dos
+func (bot *Bot) switchTodoPush(m *tb.Message, user storage.User, enabled bool) {
+	lastTodoID := 0
+	if enabled {
+		// Push only To-Dos appeared from now on
+		todos, err := bot.pendingTodos(user)
+		if err != nil {
+			bot.reply(m, "Can't get your To-Dos from GitLab, try again later")
+			return
+		}
+		for _, todo := range todos {
+			if todo.ID > lastTodoID {
+				lastTodoID = todo.ID
+			}
+		}
+	}
+	if err := bot.store.SetTodoPush(m.Sender.ID, enabled, lastTodoID); err != nil {
+		telegramLogger.Errorf("Can't save To-Do push setting of user %d: %s", m.Sender.ID, err.Error())
+		bot.reply(m, "Can't save setting, try again later")
+		return
+	}
+	if enabled {
+		bot.reply(m, "You will get a message when new To-Do appears")
+	} else {
+		bot.reply(m, "New To-Dos won't be pushed anymore")
+	}
+}
+
+// todoDoneFromCallback - mark To-Do as done and refresh the list
+func (bot *Bot) todoDoneFromCallback(c *tb.Callback, data CallbackData) {
+	if !senderChat(c) {
+		bot.respond(c, "This list belongs to another user", true)
+		return
+	}
+	user, linked := bot.store.GetUser(c.Sender.ID)
+	todoID, err := strconv.Atoi(data.Arg)
+	if !linked || user.GitlabID == 0 || !bot.conf().GitlabSudo || err != nil {
+		bot.respond(c, "Can't mark To-Do as done", true)
+		return
+	}
+	if _, err := bot.gitlab.Todos.MarkTodoAsDone(todoID, bot.actAs(user)...); err != nil {
+		telegramLogger.Errorf("Can't mark To-Do %d of user %s as done: %s", todoID, user.GitlabUsername, err.Error())
+		bot.respond(c, "GitLab request failed", true)
+		return
+	}
+	if strings.HasPrefix(c.Message.Text, todoPushTitle) {
+		bot.editMarkup(c, nil)
+	} else if text, markup, err := bot.todosMessage(user); err == nil {
+		// Message is /todos list, refresh it
+		if _, err := bot.telegram.Edit(c.Message, text, &tb.SendOptions{ParseMode: tb.ModeMarkdownV2, ReplyMarkup: markup, DisableWebPagePreview: true}); err != nil && !isNotModified(err) {
+			telegramLogger.Errorf("Can't update message: %s", err.Error())
+		}
+	}
+	bot.respond(c, "To-Do is done", false)
+}
+
+// todosMessage - render pending To-Dos with buttons
+func (bot *Bot) todosMessage(user storage.User) (string, *tb.ReplyMarkup, error) {
+	todos, err := bot.pendingTodos(user)
+	if err != nil {
+		return "", nil, err
+	}
+	var todosBuilder strings.Builder
+	todosBuilder.Grow(32)
+	if len(todos) == 0 {
+		fmt.Fprintf(&todosBuilder, "🎉 *No pending To\\-Dos*\n")
+		return todosBuilder.String(), nil, nil
+	}
+	fmt.Fprintf(&todosBuilder, "📝 *Pending To\\-Dos*\n")
+	markup := &tb.ReplyMarkup{}
+	for index, todo := range todos {
+		if index == todosPerMessage {
+			fmt.Fprintf(&todosBuilder, "\\.\\.\\. and %d more\n", len(todos)-todosPerMessage)
+			break
+		}
+		fmt.Fprintf(&todosBuilder, "%d\\. %s\n", index+1, beautifyTodo(todo))
+		markup.InlineKeyboard = append(markup.InlineKeyboard, todoButtons(bot.conf().CallbackSecret, index+1, todo))
+	}
+	return todosBuilder.String(), markup, nil
+}
+
+// pendingTodos - user's pending To-Dos, newest first
+func (bot *Bot) pendingTodos(user storage.User) ([]*gitlab.Todo, error) {
+	todos, _, err := bot.gitlab.Todos.ListTodos(&gitlab.ListTodosOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 100},
+		State:       gitlab.String("pending"),
+	}, bot.actAs(user)...)
+	return todos, err
+}
+
+// pushTodos - send new To-Dos to users who enabled push
+func (bot *Bot) pushTodos() {
+	for _, telegramID := range bot.store.UsersWithTodoPush() {
+		user, _ := bot.store.GetUser(telegramID)
+		if !user.Receives() || user.GitlabID == 0 {
+			continue
+		}
+		todos, err := bot.pendingTodos(user)
+		if err != nil {
+			telegramLogger.Errorf("Can't poll To-Dos of user %s: %s", user.GitlabUsername, err.Error())
+			continue
+		}
+		lastTodoID := user.LastTodoID
+		// Oldest first
+		for index := len(todos) - 1; index >= 0; index-- {
+			todo := todos[index]
+			if todo.ID <= user.LastTodoID {
+				continue
+			}
+			markup := &tb.ReplyMarkup{InlineKeyboard: [][]tb.InlineButton{todoButtons(bot.conf().CallbackSecret, 0, todo)}}
+			if _, err := bot.telegram.Send(&tb.Chat{ID: int64(telegramID)}, "📝 *New To\\-Do*: "+beautifyTodo(todo), &tb.SendOptions{
+				ParseMode:             tb.ModeMarkdownV2,
+				ReplyMarkup:           markup,
+				DisableWebPagePreview: true,
+			}); err != nil {
+				telegramLogger.Errorf("Can't push To-Do to user %s: %s", user.GitlabUsername, err.Error())
+				bot.checkChatGone(int64(telegramID), err)
+				break
+			}
+			lastTodoID = todo.ID
+		}
+		if lastTodoID != user.LastTodoID {
+			if err := bot.store.SetTodoPush(telegramID, true, lastTodoID); err != nil {
+				telegramLogger.Errorf("Can't save last To-Do of user %s: %s", user.GitlabUsername, err.Error())
+			}
+		}
+	}
+}
+
+// pollTodosIfDue - poll To-Dos not more often than configured interval
+func (bot *Bot) pollTodosIfDue(now time.Time) {
+	if now.Sub(bot.lastTodoPoll) < bot.conf().TodoPollInterval {
+		return
+	}
+	bot.lastTodoPoll = now
+	bot.pushTodos()
+}
+
+// beautifyTodo - one line markdown description of To-Do
+func beautifyTodo(todo *gitlab.Todo) string {
+	var title, author string
+	if todo.Target != nil {
+		title = todo.Target.Title
+	}
+	if todo.Author != nil {
+		author = todo.Author.Name
+	}
+	action := strings.ReplaceAll(string(todo.ActionName), "_", " ")
+	return fmt.Sprintf("[%s](%s) \\(%s by %s\\)", utils.SanitizeTelegramString(title), todo.TargetURL,
+		utils.SanitizeTelegramString(action), utils.SanitizeTelegramString(author))
+}
+
+// todoButtons - open and done buttons for To-Do. Number is shown in /todos list
+func todoButtons(secret []byte, number int, todo *gitlab.Todo) []tb.InlineButton {
+	openText, doneText := "🔗 Open", "✅ Done"
+	if number > 0 {
+		openText, doneText = fmt.Sprintf("🔗 %d", number), fmt.Sprintf("✅ Done %d", number)
+	}
+	return []tb.InlineButton{
+		{Text: openText, URL: todo.TargetURL},
+		callbackButton(secret, doneText, CallbackData{Action: ActionTodoDone, Arg: strconv.Itoa(todo.ID)}, issue.Attibutes{}),
+	}
+}
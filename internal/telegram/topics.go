@@ -0,0 +1,151 @@
+package telegram
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	storage "github.com/aberestyak/gitlab-issue-bot/internal/storage"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+// topicNameLimit - max length of forum topic name allowed by Telegram
+const topicNameLimit = 128
+
+const topicsUsage = "Usage inside forum group: /topics project|issue|off. Events of each project or each issue will get own topic"
+
+// handleTopics - switch forum topics mode of group chat
+func (bot *Bot) handleTopics(m *tb.Message) {
+	if m.Chat.Type != tb.ChatSuperGroup {
+		bot.reply(m, topicsUsage)
+		return
+	}
+	if !bot.isChatAdmin(m.Chat, m.Sender) {
+		bot.reply(m, "Only chat administrators can change topics mode")
+		return
+	}
+	mode := strings.TrimSpace(m.Payload)
+	switch mode {
+	case storage.TopicsPerProject, storage.TopicsPerIssue:
+	case "off":
+		mode = ""
+	default:
+		bot.reply(m, topicsUsage)
+		return
+	}
+	if err := bot.store.SetTopicMode(m.Chat.ID, mode); err != nil {
+		telegramLogger.Errorf("Can't save topics mode of chat %d: %s", m.Chat.ID, err.Error())
+		bot.reply(m, "Can't save topics mode, try again later")
+		return
+	}
+	if mode == "" {
+		bot.reply(m, "Events will be sent to General topic")
+	} else {
+		bot.reply(m, fmt.Sprintf("Events of each %s will be sent to its own topic. Bot needs Manage topics right", mode))
+	}
+}
+
+// sendNotification - send notification text to chat, into forum topic if chat uses them
+func (bot *Bot) sendNotification(chatID int64, n *notification, options *tb.SendOptions) (*tb.Message, error) {
+	mode := bot.store.TopicMode(chatID)
+	if mode == "" {
+		return bot.telegram.Send(&tb.Chat{ID: chatID}, n.Text, options)
+	}
+	subject, name := topicOf(mode, n)
+	for attempt := 0; ; attempt++ {
+		topicID, err := bot.topicFor(chatID, subject, name)
+		if err != nil {
+			telegramLogger.Errorf("Issue #%d. Can't create topic in chat %d, use General: %s", n.Attributes.ID, chatID, err.Error())
+			return bot.telegram.Send(&tb.Chat{ID: chatID}, n.Text, options)
+		}
+		message, err := bot.sendToTopic(chatID, topicID, n.Text, options)
+		if err == nil || !isTopicGone(err) || attempt > 0 {
+			return message, err
+		}
+		// Topic was deleted by chat admin, create new one
+		telegramLogger.Warnf("Topic %d of chat %d is gone, recreate it", topicID, chatID)
+		if err := bot.store.SaveTopic(chatID, subject, 0); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// topicOf - storage subject and name of topic notification belongs to
+func topicOf(mode string, n *notification) (string, string) {
+	if mode == storage.TopicsPerIssue {
+		name := fmt.Sprintf("%s#%d %s", n.ProjectPath, n.Attributes.ID, n.Attributes.Title)
+		return "issue:" + n.issueKey(), truncateRunes(name, topicNameLimit)
+	}
+	return "project:" + n.ProjectPath, truncateRunes(n.ProjectPath, topicNameLimit)
+}
+
+// topicFor - get remembered topic or create new one
+func (bot *Bot) topicFor(chatID int64, subject string, name string) (int, error) {
+	if topicID, found := bot.store.GetTopic(chatID, subject); found {
+		return topicID, nil
+	}
+	data, err := bot.telegram.Raw("createForumTopic", map[string]interface{}{
+		"chat_id": chatID,
+		"name":    name,
+	})
+	if err != nil {
+		return 0, err
+	}
+	var response struct {
+		Result struct {
+			MessageThreadID int `json:"message_thread_id"`
+		}
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return 0, err
+	}
+	topicID := response.Result.MessageThreadID
+	telegramLogger.Infof("Topic %d \"%s\" created in chat %d", topicID, name, chatID)
+	return topicID, bot.store.SaveTopic(chatID, subject, topicID)
+}
+
+// sendToTopic - send message into forum topic. Telebot doesn't support topics, so use raw API
+func (bot *Bot) sendToTopic(chatID int64, topicID int, text string, options *tb.SendOptions) (*tb.Message, error) {
+	payload := map[string]interface{}{
+		"chat_id":           chatID,
+		"message_thread_id": topicID,
+		"text":              text,
+	}
+	if options.ParseMode != tb.ModeDefault {
+		payload["parse_mode"] = options.ParseMode
+	}
+	if options.ReplyMarkup != nil {
+		payload["reply_markup"] = options.ReplyMarkup
+	}
+	if options.ReplyTo != nil {
+		payload["reply_to_message_id"] = options.ReplyTo.ID
+		payload["allow_sending_without_reply"] = options.AllowWithoutReply
+	}
+	if options.DisableNotification {
+		payload["disable_notification"] = true
+	}
+	data, err := bot.telegram.Raw("sendMessage", payload)
+	if err != nil {
+		return nil, err
+	}
+	var response struct {
+		Result *tb.Message
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, err
+	}
+	return response.Result, nil
+}
+
+// isTopicGone - check error means topic was deleted
+func isTopicGone(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "message thread not found")
+}
+
+// truncateRunes - cut text to limit runes
+func truncateRunes(text string, limit int) string {
+	if runes := []rune(text); len(runes) > limit {
+		return string(runes[:limit])
+	}
+	return text
+}
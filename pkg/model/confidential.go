@@ -0,0 +1,38 @@
+package issue
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Webhook object kinds of issues and comments
+const (
+	KindIssue             = "issue"
+	KindConfidentialIssue = "confidential_issue"
+	KindNote              = "note"
+	KindConfidentialNote  = "confidential_note"
+)
+
+// confidentialMark - line marking notifications about confidential issues
+const confidentialMark = "🔒 *Confidential issue*"
+
+// RedactedNotification - markdown notification about confidential issue or internal comment
+// without their content, only issue number and link
+func RedactedNotification(attributes Attibutes, internalNote bool) string {
+	var redactedBuilder strings.Builder
+	issueID := strconv.Itoa(attributes.ID)
+	switch {
+	case internalNote:
+		fmt.Fprintf(&redactedBuilder, "🔒 *Internal comment in [\\#%s](%s)*\n", issueID, attributes.URL)
+	case attributes.Action == "open":
+		fmt.Fprintf(&redactedBuilder, "🔒 *Confidential issue [\\#%s](%s) opened*\n", issueID, attributes.URL)
+	case attributes.Action == "close":
+		fmt.Fprintf(&redactedBuilder, "🔒 *Confidential issue [\\#%s](%s) closed*\n", issueID, attributes.URL)
+	case attributes.Action == "reopen":
+		fmt.Fprintf(&redactedBuilder, "🔒 *Confidential issue [\\#%s](%s) reopened*\n", issueID, attributes.URL)
+	default:
+		fmt.Fprintf(&redactedBuilder, "🔒 *Confidential issue [\\#%s](%s) updated*\n", issueID, attributes.URL)
+	}
+	return redactedBuilder.String()
+}
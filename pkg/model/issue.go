@@ -11,11 +11,16 @@ import (
 type Issue struct {
 	IssueBody *BodySpec
 	IssueNote *NoteSpec
+	// SystemHook - user event of system hook, issue and note are empty then
+	SystemHook *SystemHookSpec
+	// Instance - name of gitlab instance the event came from
+	Instance string
 }
 
 // Attibutes - issue attributes
 type Attibutes struct {
 	ID                  int `json:"iid"`
+	ProjectID           int `json:"project_id"`
 	IssueBodyAuthor     int `json:"author_id"`
 	IssueBodyAuthorName string
 	Assignee            []int `json:"assignee_ids"`
@@ -28,6 +33,8 @@ type Attibutes struct {
 	Action              string   `json:"action"`
 	Description         string   `json:"description"`
 	Title               string   `json:"title"`
+	Type                string   `json:"type"`
+	Confidential        bool     `json:"confidential"`
 }
 
 // Labels - issue labels
@@ -36,6 +43,33 @@ type Labels struct {
 	Title string `json:"title"`
 }
 
+// Project - project the issue belongs to
+type Project struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	WebURL            string `json:"web_url"`
+	VisibilityLevel   int    `json:"visibility_level"`
+}
+
+// Visibility levels of projects
+const (
+	// VisibilityPrivate - visible to members only
+	VisibilityPrivate = 0
+	// VisibilityPublic - visible to everyone
+	VisibilityPublic = 20
+)
+
+// IsPrivate - project is visible to its members only
+func (project Project) IsPrivate() bool {
+	return project.VisibilityLevel == VisibilityPrivate
+}
+
+// IsPublic - project is visible to everyone
+func (project Project) IsPublic() bool {
+	return project.VisibilityLevel == VisibilityPublic
+}
+
 // Author - issue author
 type Author struct {
 	Name string `json:"name"`
@@ -47,6 +81,37 @@ type BotUser struct {
 	Name       string
 	TelegramID int
 	GitlabID   int
+	Mentioned  bool
+}
+
+// IsMentioned - user was found by mention, not by issue participation
+func (user BotUser) IsMentioned() bool {
+	return user.Mentioned
+}
+
+// AttributesFromGitlab - convert issue received from gitlab API into webhook attributes
+func AttributesFromGitlab(gitlabIssue *gitlab.Issue) Attibutes {
+	attributes := Attibutes{
+		ID:           gitlabIssue.IID,
+		ProjectID:    gitlabIssue.ProjectID,
+		State:        gitlabIssue.State,
+		URL:          gitlabIssue.WebURL,
+		Description:  gitlabIssue.Description,
+		Title:        gitlabIssue.Title,
+		Confidential: gitlabIssue.Confidential,
+	}
+	if gitlabIssue.Author != nil {
+		attributes.IssueBodyAuthor = gitlabIssue.Author.ID
+		attributes.IssueBodyAuthorName = gitlabIssue.Author.Name
+	}
+	for _, assignee := range gitlabIssue.Assignees {
+		attributes.Assignee = append(attributes.Assignee, assignee.ID)
+		attributes.AssigneeNames = append(attributes.AssigneeNames, assignee.Name)
+	}
+	for _, label := range gitlabIssue.Labels {
+		attributes.Labels = append(attributes.Labels, Labels{Title: label})
+	}
+	return attributes
 }
 
 func (issue *Issue) CreateUsersList(gitlabClient *gitlab.Client) ([]BotUser, error) {
@@ -86,11 +151,11 @@ func makeUniqUsersList(gitlabUsersIDs []int, gitlabUsersNames []string, gitlabCl
 		usersList = appendUniq(BotUser{GitlabID: gitlabUserID, TelegramID: telegramID, Name: name}, usersList, telegramID)
 	}
 	for _, gitlabUserName := range gitlabUsersNames {
-		telegramID, err := gitlabUserAPI.GetTgIDByGitlabUsername(gitlabUserName, gitlabClient)
+		telegramID, gitlabUserID, err := gitlabUserAPI.GetIDsByGitlabUsername(gitlabUserName, gitlabClient)
 		if err != nil {
 			return nil, err
 		}
-		usersList = appendUniq(BotUser{TelegramID: telegramID, GitlabID: -1, Name: gitlabUserName}, usersList, telegramID)
+		usersList = appendUniq(BotUser{TelegramID: telegramID, GitlabID: gitlabUserID, Mentioned: true, Name: gitlabUserName}, usersList, telegramID)
 	}
 	return usersList, nil
 }
@@ -98,8 +163,9 @@ func makeUniqUsersList(gitlabUsersIDs []int, gitlabUsersNames []string, gitlabCl
 // appendUniq - append only uniq users
 func appendUniq(user BotUser, usersList []BotUser, key int) []BotUser {
 	found := false
-	for _, user := range usersList {
-		if user.TelegramID == key {
+	for _, listed := range usersList {
+		// Users without Telegram are told apart by names
+		if listed.TelegramID == key && (key != 0 || listed.Name == user.Name) {
 			found = true
 		}
 	}
@@ -1,6 +1,7 @@
 package issue
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -12,9 +13,54 @@ import (
 
 // BodySpec - issue itself spec
 type BodySpec struct {
-	Kind             string    `json:"object_kind"`
-	User             Author    `json:"user"`
-	ObjectAttributes Attibutes `json:"object_attributes"`
+	Kind             string                     `json:"object_kind"`
+	User             Author                     `json:"user"`
+	Project          Project                    `json:"project"`
+	ObjectAttributes Attibutes                  `json:"object_attributes"`
+	Changes          map[string]json.RawMessage `json:"changes"`
+}
+
+// changesIgnoredByLabelsCheck - changes which accompany any update
+var changesIgnoredByLabelsCheck = map[string]bool{
+	"updated_at":    true,
+	"updated_by_id": true,
+}
+
+// AssigneesChanged - check if update changed issue assignees
+func (issueBody *BodySpec) AssigneesChanged() bool {
+	_, found := issueBody.Changes["assignees"]
+	return issueBody.ObjectAttributes.Action == "update" && found
+}
+
+// LabelsOnlyUpdate - check if update changed nothing but labels
+func (issueBody *BodySpec) LabelsOnlyUpdate() bool {
+	if issueBody.ObjectAttributes.Action != "update" {
+		return false
+	}
+	if _, found := issueBody.Changes["labels"]; !found {
+		return false
+	}
+	for change := range issueBody.Changes {
+		if change != "labels" && !changesIgnoredByLabelsCheck[change] {
+			return false
+		}
+	}
+	return true
+}
+
+// PreviousLabels - labels before update, false if update didn't change labels
+func (issueBody *BodySpec) PreviousLabels() ([]Labels, bool) {
+	rawChange, found := issueBody.Changes["labels"]
+	if !found {
+		return nil, false
+	}
+	var change struct {
+		Previous []Labels `json:"previous"`
+	}
+	if err := json.Unmarshal(rawChange, &change); err != nil {
+		return nil, false
+	}
+	return change.Previous, true
 }
 
 // GetUsersIDs - get gitlab IDs of all involved users
@@ -66,6 +112,9 @@ func (issueBody *BodySpec) BeautifyNotification() string {
 	case "reopen":
 		fmt.Fprintf(&issueBodyBuilder, "♾ *Issue reopened [\\#%s](%s)*\n", issueID, issueBody.ObjectAttributes.URL)
 	}
+	if issueBody.ObjectAttributes.Confidential {
+		fmt.Fprintf(&issueBodyBuilder, "%s\n", confidentialMark)
+	}
 	fmt.Fprintf(&issueBodyBuilder, "*Name*: %s\n", utils.SanitizeTelegramString(issueBody.ObjectAttributes.Title))
 	fmt.Fprintf(&issueBodyBuilder, "*Creator*: %s\n", issueBody.ObjectAttributes.IssueBodyAuthorName)
 	if len(issueBody.ObjectAttributes.AssigneeNames) > 0 {
@@ -0,0 +1,62 @@
+package issue
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	utils "github.com/aberestyak/gitlab-issue-bot/utils"
+)
+
+const commentExcerptLength = 200
+
+// IssueCard - issue summary kept up to date in single message
+type IssueCard struct {
+	Attributes        Attibutes
+	LastComment       string
+	LastCommentAuthor string
+}
+
+// BeautifyNotification - generate beautiful markdown issue card
+func (card *IssueCard) BeautifyNotification() string {
+	var cardBuilder strings.Builder
+	issueID := strconv.Itoa(card.Attributes.ID)
+	cardBuilder.Grow(32)
+	stateIcon := "🟢"
+	if card.Attributes.State == "closed" {
+		stateIcon = "🔴"
+	}
+	fmt.Fprintf(&cardBuilder, "📋 *Issue [\\#%s](%s)*\n", issueID, card.Attributes.URL)
+	fmt.Fprintf(&cardBuilder, "*State*: %s %s\n", stateIcon, utils.SanitizeTelegramString(card.Attributes.State))
+	if card.Attributes.Confidential {
+		fmt.Fprintf(&cardBuilder, "%s\n", confidentialMark)
+	}
+	fmt.Fprintf(&cardBuilder, "*Name*: %s\n", utils.SanitizeTelegramString(card.Attributes.Title))
+	fmt.Fprintf(&cardBuilder, "*Creator*: %s\n", card.Attributes.IssueBodyAuthorName)
+	if len(card.Attributes.AssigneeNames) > 0 {
+		fmt.Fprintf(&cardBuilder, "*Assignee*:\n")
+		for _, AssigneeName := range card.Attributes.AssigneeNames {
+			fmt.Fprintf(&cardBuilder, "  ◦ %s\n", AssigneeName)
+		}
+	}
+	if len(card.Attributes.Labels) > 0 {
+		fmt.Fprintf(&cardBuilder, "*Labels*:\n")
+		for _, label := range card.Attributes.Labels {
+			fmt.Fprintf(&cardBuilder, "  ◦ %s\n", utils.SanitizeTelegramString(label.Title))
+		}
+	}
+	if card.LastComment != "" {
+		fmt.Fprintf(&cardBuilder, "*Last comment by %s*: %s\n", card.LastCommentAuthor, utils.SanitizeTelegramString(Excerpt(card.LastComment, commentExcerptLength)))
+	}
+	return cardBuilder.String()
+}
+
+// Excerpt - cut text to limit runes
+func Excerpt(text string, limit int) string {
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	return string([]rune(text)[:limit]) + "…"
+}
@@ -14,17 +14,26 @@ import (
 type NoteSpec struct {
 	Kind             string         `json:"object_kind"`
 	User             Author         `json:"user"`
+	Project          Project        `json:"project"`
 	ObjectAttributes NotesAttibutes `json:"object_attributes"`
 	Issue            Attibutes      `json:"issue"`
 }
 
 // NotesAttibutes - issue comment attributes
 type NotesAttibutes struct {
-	Note        string `json:"note"`
-	Description string `json:"description"`
-	URL         string `json:"URL"`
+	ID           int    `json:"id"`
+	Note         string `json:"note"`
+	Description  string `json:"description"`
+	URL          string `json:"URL"`
+	Type         string `json:"type"`
+	DiscussionID string `json:"discussion_id"`
+	Internal     bool   `json:"internal"`
+	Confidential bool   `json:"confidential"`
 }
 
+// DiscussionNoteType - type of notes which are part of a thread
+const DiscussionNoteType = "DiscussionNote"
+
 // GetUsersIDs - get gitlab IDs of all involved users
 func (issueNote *NoteSpec) GetUsersIDs() []int {
 	return append(issueNote.getAssignee(), issueNote.getAuthor())
@@ -59,7 +68,14 @@ func (issueNote *NoteSpec) BeautifyNotification() string {
 	var noteTextBuilder strings.Builder
 	issueID := strconv.Itoa(issueNote.Issue.ID)
 	noteTextBuilder.Grow(32)
-	fmt.Fprintf(&noteTextBuilder, "💬 *New comment in [\\#%s](%s)*\n", issueID, issueNote.Issue.URL)
+	if issueNote.ObjectAttributes.Internal {
+		fmt.Fprintf(&noteTextBuilder, "🔒 *New internal comment in [\\#%s](%s)*\n", issueID, issueNote.Issue.URL)
+	} else {
+		fmt.Fprintf(&noteTextBuilder, "💬 *New comment in [\\#%s](%s)*\n", issueID, issueNote.Issue.URL)
+	}
+	if issueNote.Issue.Confidential {
+		fmt.Fprintf(&noteTextBuilder, "%s\n", confidentialMark)
+	}
 	fmt.Fprintf(&noteTextBuilder, "*Issue*:\n")
 	fmt.Fprintf(&noteTextBuilder, "*  Name*: %s\n", utils.SanitizeTelegramString(issueNote.Issue.Title))
 	fmt.Fprintf(&noteTextBuilder, "*  Creator*: %s\n", issueNote.Issue.IssueBodyAuthorName)
@@ -0,0 +1,157 @@
+package issue
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	utils "github.com/aberestyak/gitlab-issue-bot/utils"
+)
+
+// SummaryEvent - issue event postponed to be sent in summary
+type SummaryEvent struct {
+	ProjectPath string    `json:"project_path"`
+	IssueIID    int       `json:"issue_iid"`
+	IssueURL    string    `json:"issue_url"`
+	IssueTitle  string    `json:"issue_title"`
+	Kind        string    `json:"kind"`
+	At          time.Time `json:"at"`
+}
+
+// Summary - postponed events grouped by project and issue
+type Summary struct {
+	Title  string
+	Events []SummaryEvent
+}
+
+// summaryKinds - how to describe events of kind in summary. Order matters
+var summaryKinds = []struct {
+	Kind     string
+	Singular string
+	Plural   string
+}{
+	{"open", "opened", "opened"},
+	{"comment", "1 comment", "%d comments"},
+	{"update", "1 update", "%d updates"},
+	{"assign", "1 new assignment", "%d new assignments"},
+	{"close", "closed", "closed"},
+	{"reopen", "reopened", "reopened"},
+}
+
+// MessageLimit - max length of telegram message text in UTF-16 code units
+const MessageLimit = 4096
+
+// Split - split summary into parts whose notifications fit into limit. Events of one issue
+// stay in the same part, issues are ordered by project
+func (summary *Summary) Split(limit int) []*Summary {
+	var keys []string
+	byIssue := map[string][]SummaryEvent{}
+	for _, event := range summary.Events {
+		key := fmt.Sprintf("%s#%d", event.ProjectPath, event.IssueIID)
+		if _, found := byIssue[key]; !found {
+			keys = append(keys, key)
+		}
+		byIssue[key] = append(byIssue[key], event)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return byIssue[keys[i]][0].ProjectPath < byIssue[keys[j]][0].ProjectPath
+	})
+	var parts []*Summary
+	current := &Summary{Title: summary.Title}
+	for _, key := range keys {
+		candidate := &Summary{Title: summary.Title, Events: append(append([]SummaryEvent{}, current.Events...), byIssue[key]...)}
+		if len(current.Events) > 0 && messageLength(candidate.BeautifyNotification()) > limit {
+			parts = append(parts, current)
+			candidate = &Summary{Title: summary.Title, Events: byIssue[key]}
+		}
+		current = candidate
+	}
+	if len(current.Events) > 0 {
+		parts = append(parts, current)
+	}
+	return parts
+}
+
+// SplitLines - join lines into messages no longer than limit. Lines are not broken,
+// so line longer than limit makes a message of its own
+func SplitLines(lines []string, limit int) []string {
+	var messages []string
+	var current strings.Builder
+	length := 0
+	for index, line := range lines {
+		lineLength := messageLength(line)
+		if index > 0 && length+1+lineLength > limit {
+			messages = append(messages, current.String())
+			current.Reset()
+			length = 0
+		} else if index > 0 {
+			current.WriteString("\n")
+			length++
+		}
+		current.WriteString(line)
+		length += lineLength
+	}
+	if len(lines) > 0 {
+		messages = append(messages, current.String())
+	}
+	return messages
+}
+
+// messageLength - length of text as counted by telegram
+func messageLength(text string) int {
+	return len(utf16.Encode([]rune(text)))
+}
+
+// BeautifyNotification - generate beautiful markdown summary
+func (summary *Summary) BeautifyNotification() string {
+	var summaryBuilder strings.Builder
+	summaryBuilder.Grow(32)
+	fmt.Fprintf(&summaryBuilder, "*%s*\n", utils.SanitizeTelegramString(summary.Title))
+
+	type issueEvents struct {
+		Event SummaryEvent
+		Kinds map[string]int
+	}
+	projects := map[string][]*issueEvents{}
+	issues := map[string]*issueEvents{}
+	for _, event := range summary.Events {
+		key := fmt.Sprintf("%s#%d", event.ProjectPath, event.IssueIID)
+		events, found := issues[key]
+		if !found {
+			events = &issueEvents{Event: event, Kinds: map[string]int{}}
+			issues[key] = events
+			projects[event.ProjectPath] = append(projects[event.ProjectPath], events)
+		}
+		// Keep the latest title
+		events.Event.IssueTitle = event.IssueTitle
+		events.Kinds[event.Kind]++
+	}
+
+	projectPaths := make([]string, 0, len(projects))
+	for projectPath := range projects {
+		projectPaths = append(projectPaths, projectPath)
+	}
+	sort.Strings(projectPaths)
+	for _, projectPath := range projectPaths {
+		fmt.Fprintf(&summaryBuilder, "\n*%s*\n", utils.SanitizeTelegramString(projectPath))
+		for _, events := range projects[projectPath] {
+			var kinds []string
+			for _, summaryKind := range summaryKinds {
+				count := events.Kinds[summaryKind.Kind]
+				switch {
+				case count == 1:
+					kinds = append(kinds, summaryKind.Singular)
+				case count > 1 && strings.Contains(summaryKind.Plural, "%d"):
+					kinds = append(kinds, fmt.Sprintf(summaryKind.Plural, count))
+				case count > 1:
+					kinds = append(kinds, summaryKind.Plural)
+				}
+			}
+			fmt.Fprintf(&summaryBuilder, "  ◦ [\\#%d](%s) %s: %s\n", events.Event.IssueIID, events.Event.IssueURL,
+				utils.SanitizeTelegramString(events.Event.IssueTitle), utils.SanitizeTelegramString(strings.Join(kinds, ", ")))
+		}
+	}
+	return summaryBuilder.String()
+}
@@ -0,0 +1,105 @@
+package issue
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarySplit(t *testing.T) {
+	at := time.Date(2022, 3, 1, 9, 0, 0, 0, time.UTC)
+	var events []SummaryEvent
+	for project := 0; project < 5; project++ {
+		for iid := 1; iid <= 60; iid++ {
+			for _, kind := range []string{"comment", "update", "comment"} {
+				events = append(events, SummaryEvent{
+					ProjectPath: fmt.Sprintf("group/project-%d", project),
+					IssueIID:    iid,
+					IssueURL:    fmt.Sprintf("https://gitlab.example.com/group/project-%d/-/issues/%d", project, iid),
+					IssueTitle:  "Очень длинное название задачи " + strings.Repeat("💥", 20),
+					Kind:        kind,
+					At:          at,
+				})
+			}
+		}
+	}
+	summary := &Summary{Title: "📰 Digest", Events: events}
+	if length := messageLength(summary.BeautifyNotification()); length <= MessageLimit {
+		t.Fatalf("test summary is too small: %d", length)
+	}
+
+	parts := summary.Split(MessageLimit)
+	if len(parts) < 2 {
+		t.Fatalf("got %d parts, want several", len(parts))
+	}
+	total := 0
+	issueParts := map[string]int{}
+	for index, part := range parts {
+		if length := messageLength(part.BeautifyNotification()); length > MessageLimit {
+			t.Errorf("part %d is %d long", index, length)
+		}
+		for _, event := range part.Events {
+			key := fmt.Sprintf("%s#%d", event.ProjectPath, event.IssueIID)
+			if previous, found := issueParts[key]; found && previous != index {
+				t.Errorf("events of %s are in parts %d and %d", key, previous, index)
+			}
+			issueParts[key] = index
+		}
+		total += len(part.Events)
+	}
+	if total != len(events) {
+		t.Errorf("parts have %d events, want %d", total, len(events))
+	}
+}
+
+func TestSummarySplitSmall(t *testing.T) {
+	summary := &Summary{Title: "🌙 While you were away", Events: []SummaryEvent{
+		{ProjectPath: "group/api", IssueIID: 1, IssueURL: "https://gitlab.example.com/group/api/-/issues/1", Kind: "open"},
+		{ProjectPath: "group/api", IssueIID: 1, IssueURL: "https://gitlab.example.com/group/api/-/issues/1", Kind: "comment"},
+	}}
+	parts := summary.Split(MessageLimit)
+	if len(parts) != 1 || len(parts[0].Events) != 2 {
+		t.Fatalf("got %d parts, want single part with all events", len(parts))
+	}
+	if (&Summary{Title: "empty"}).Split(MessageLimit) != nil {
+		t.Errorf("empty summary has parts")
+	}
+}
+
+func TestSplitLines(t *testing.T) {
+	var lines []string
+	for index := 0; index < 500; index++ {
+		lines = append(lines, fmt.Sprintf("user-%d — %d (другой@secondary), active", index, 100000000+index))
+	}
+	messages := SplitLines(lines, MessageLimit)
+	if len(messages) < 2 {
+		t.Fatalf("got %d messages, want several", len(messages))
+	}
+	for index, message := range messages {
+		if length := messageLength(message); length > MessageLimit {
+			t.Errorf("message %d is %d long", index, length)
+		}
+	}
+	if joined := strings.Join(messages, "\n"); joined != strings.Join(lines, "\n") {
+		t.Errorf("messages don't keep lines")
+	}
+
+	tests := []struct {
+		lines []string
+		limit int
+		want  []string
+	}{
+		{nil, 10, nil},
+		{[]string{"one"}, 10, []string{"one"}},
+		{[]string{"one", "two"}, 7, []string{"one\ntwo"}},
+		{[]string{"one", "two"}, 6, []string{"one", "two"}},
+		{[]string{"long line", "x"}, 4, []string{"long line", "x"}},
+	}
+	for _, test := range tests {
+		got := SplitLines(test.lines, test.limit)
+		if strings.Join(got, "|") != strings.Join(test.want, "|") || len(got) != len(test.want) {
+			t.Errorf("SplitLines(%q, %d) = %q, want %q", test.lines, test.limit, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package issue
+
+import "strings"
+
+// System hook events handled by the bot
+const (
+	SystemUserCreate       = "user_create"
+	SystemUserDestroy      = "user_destroy"
+	SystemUserFailedLogin  = "user_failed_login"
+	SystemUserAddToTeam    = "user_add_to_team"
+	SystemUserAddToGroup   = "user_add_to_group"
+	SystemUserStateBlocked = "blocked"
+	systemHookEventsPrefix = "user_"
+)
+
+// SystemHookSpec - gitlab system hook about users and their membership
+type SystemHookSpec struct {
+	EventName   string `json:"event_name"`
+	UserID      int    `json:"user_id"`
+	Username    string `json:"username"`
+	Name        string `json:"name"`
+	State       string `json:"state"`
+	ProjectPath string `json:"project_path_with_namespace"`
+	// ProjectVisibility - private, internal or public, sent with project membership events
+	ProjectVisibility string `json:"project_visibility"`
+	GroupPath         string `json:"group_path"`
+	// Membership events name user fields with user_ prefix
+	MemberUsername string `json:"user_username"`
+	MemberName     string `json:"user_name"`
+	AccessLevel    string `json:"access_level"`
+	GroupAccess    string `json:"group_access"`
+}
+
+// IsUserEvent - check if system hook event is about users
+func IsUserEvent(eventName string) bool {
+	return strings.HasPrefix(eventName, systemHookEventsPrefix)
+}
+
+// Login - username of user the event is about
+func (systemHook *SystemHookSpec) Login() string {
+	if systemHook.MemberUsername != "" {
+		return systemHook.MemberUsername
+	}
+	return systemHook.Username
+}
+
+// DisplayName - name of user the event is about
+func (systemHook *SystemHookSpec) DisplayName() string {
+	if systemHook.MemberName != "" {
+		return systemHook.MemberName
+	}
+	return systemHook.Name
+}
+
+// Blocked - user was blocked or deleted
+func (systemHook *SystemHookSpec) Blocked() bool {
+	return systemHook.EventName == SystemUserDestroy ||
+		(systemHook.EventName == SystemUserFailedLogin && systemHook.State == SystemUserStateBlocked)
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"path"
 	"regexp"
 	"strings"
 	"unicode/utf8"
@@ -69,3 +70,12 @@ func quoteMeta(s string, special [16]byte) string {
 	}
 	return string(b[:j])
 }
+
+// MatchPath - match gitlab path against pattern. Pattern "group/*" matches all projects in group and its subgroups
+func MatchPath(pattern string, value string) bool {
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(value, strings.TrimSuffix(pattern, "*"))
+	}
+	matched, err := path.Match(pattern, value)
+	return err == nil && matched
+}
@@ -0,0 +1,30 @@
+package utils
+
+import "testing"
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		want    bool
+	}{
+		{"group/*", "group/project", true},
+		{"group/*", "group/sub/project", true},
+		{"group/*", "groupX/project", false},
+		{"group/*", "group", false},
+		{"group/*", "other/group/project", false},
+		{"group/project", "group/project", true},
+		{"group/project", "group/project2", false},
+		{"group/api-*", "group/api-gateway", true},
+		{"group/api-*", "group/sub/api-gateway", false},
+		{"priority::*", "priority::high", true},
+		{"bug", "bug", true},
+		{"bug", "bugfix", false},
+		{"[", "[", false},
+	}
+	for _, test := range tests {
+		if got := MatchPath(test.pattern, test.value); got != test.want {
+			t.Errorf("MatchPath(%q, %q) = %t, want %t", test.pattern, test.value, got, test.want)
+		}
+	}
+}